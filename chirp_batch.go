@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpBatch is what the responses of a list of chirps need besides the
+// chirps themselves. Each kind is loaded with one query for the whole list
+// rather than one per chirp. Whatever fails to load is logged and left out
+// of the responses.
+type chirpBatch struct {
+	//the chirps rechirps reference, by id
+	originals map[uuid.UUID]database.Chirp
+	//lowercased handles mentioned by each chirp, to the user they resolved to
+	mentions map[uuid.UUID]map[string]uuid.UUID
+	//like and reply counts, by chirp
+	counts map[uuid.UUID]database.GetChirpsCountsRow
+	//attached media, by chirp in attachment order
+	media map[uuid.UUID][]mediaResponse
+	//link previews, by chirp in the order the links appear
+	previews map[uuid.UUID][]linkPreviewResponse
+	//tallied polls of the chirps that have one
+	polls map[uuid.UUID]*pollResponse
+}
+
+// loadChirpBatch loads what chirpsToResponse needs for chirps and the
+// originals of its rechirps
+func (cfg *apiConfig) loadChirpBatch(ctx context.Context, chirps []database.Chirp) chirpBatch {
+
+	batch := chirpBatch{
+		originals: map[uuid.UUID]database.Chirp{},
+		mentions:  map[uuid.UUID]map[string]uuid.UUID{},
+		counts:    map[uuid.UUID]database.GetChirpsCountsRow{},
+		media:     map[uuid.UUID][]mediaResponse{},
+		previews:  map[uuid.UUID][]linkPreviewResponse{},
+		polls:     map[uuid.UUID]*pollResponse{},
+	}
+
+	//the originals are loaded alongside, without touching the caller's slice
+	chirps = append([]database.Chirp{}, chirps...)
+
+	var originalIDs []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.Kind != chirpKindChirp && chirp.OriginalChirpID.Valid {
+			originalIDs = append(originalIDs, chirp.OriginalChirpID.UUID)
+		}
+	}
+
+	if len(originalIDs) > 0 {
+		originals, err := cfg.db.GetChirpsByIDs(ctx, originalIDs)
+
+		if err != nil {
+			log.Printf("Failed to retreive original chirps: %v", err)
+		}
+
+		for _, original := range originals {
+			batch.originals[original.ID] = original
+			chirps = append(chirps, original)
+		}
+	}
+
+	if len(chirps) == 0 {
+		return batch
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	counts, err := cfg.db.GetChirpsCounts(ctx, ids)
+
+	if err != nil {
+		log.Printf("Failed to count likes and replies: %v", err)
+	}
+
+	for _, row := range counts {
+		batch.counts[row.ChirpID] = row
+	}
+
+	mentioned, err := cfg.db.GetChirpsMentions(ctx, ids)
+
+	if err != nil {
+		log.Printf("Failed to retreive mentions: %v", err)
+	}
+
+	for _, row := range mentioned {
+		if batch.mentions[row.ChirpID] == nil {
+			batch.mentions[row.ChirpID] = map[string]uuid.UUID{}
+		}
+		batch.mentions[row.ChirpID][strings.ToLower(row.Handle.String)] = row.ID
+	}
+
+	attachments, err := cfg.db.GetChirpsMedia(ctx, ids)
+
+	if err != nil {
+		log.Printf("Failed to retreive media: %v", err)
+	}
+
+	//rows come in attachment order
+	for _, row := range attachments {
+		batch.media[row.ChirpID] = append(batch.media[row.ChirpID], mediaToResponse(row.MediaFile))
+	}
+
+	cfg.loadBatchPolls(ctx, ids, batch.polls)
+
+	if cfg.linkPreviews == nil {
+		return batch
+	}
+
+	var urls []string
+	for _, chirp := range chirps {
+		urls = append(urls, linkPreviewURLs(chirp)...)
+	}
+
+	if len(urls) == 0 {
+		return batch
+	}
+
+	rows, err := cfg.db.GetLinkPreviews(ctx, urls)
+
+	if err != nil {
+		log.Printf("Failed to retreive link previews: %v", err)
+		return batch
+	}
+
+	stored := map[string]database.LinkPreview{}
+	for _, row := range rows {
+		stored[row.Url] = row
+	}
+
+	//links without a fresh preview are queued for fetching along the way
+	for _, chirp := range chirps {
+		batch.previews[chirp.ID] = cfg.chirpLinkPreviews(chirp, stored)
+	}
+
+	return batch
+}
+
+// loadBatchPolls tallies the polls of the chirps that have one into polls
+func (cfg *apiConfig) loadBatchPolls(ctx context.Context, ids []uuid.UUID, polls map[uuid.UUID]*pollResponse) {
+
+	stored, err := cfg.db.GetPolls(ctx, ids)
+
+	if err != nil {
+		log.Printf("Failed to retreive polls: %v", err)
+		return
+	}
+
+	if len(stored) == 0 {
+		return
+	}
+
+	pollIDs := make([]uuid.UUID, 0, len(stored))
+	for _, row := range stored {
+		pollIDs = append(pollIDs, row.ChirpID)
+	}
+
+	results, err := cfg.db.GetPollsResults(ctx, pollIDs)
+
+	if err != nil {
+		log.Printf("Failed to retreive poll results: %v", err)
+		return
+	}
+
+	//rows come in position order
+	options := map[uuid.UUID][]database.GetPollResultsRow{}
+	for _, row := range results {
+		options[row.ChirpID] = append(options[row.ChirpID], database.GetPollResultsRow{
+			Position: row.Position,
+			Text:     row.Text,
+			Votes:    row.Votes,
+		})
+	}
+
+	for _, row := range stored {
+		res := pollToResponse(row, options[row.ChirpID])
+		polls[row.ChirpID] = &res
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type relationshipResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type relationshipListResponse struct {
+	Users      []relationshipResponse `json:"users"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// hiddenUsers are the authors whose chirps a viewer doesn't see: users
+// blocked either way and users the viewer muted. A nil set hides nobody.
+type hiddenUsers map[uuid.UUID]bool
+
+// hiddenUsersFor loads what viewerID doesn't see, anonymous viewers see
+// everything
+func (cfg *apiConfig) hiddenUsersFor(ctx context.Context, viewerID uuid.UUID) (hiddenUsers, error) {
+
+	if viewerID == uuid.Nil {
+		return nil, nil
+	}
+
+	ids, err := cfg.db.GetHiddenUserIDs(ctx, viewerID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(hiddenUsers, len(ids))
+	for _, id := range ids {
+		hidden[id] = true
+	}
+
+	return hidden, nil
+}
+
+// filter drops the chirps of hidden authors, keeping the order
+func (hidden hiddenUsers) filter(chirps []database.Chirp) []database.Chirp {
+
+	if len(hidden) == 0 {
+		return chirps
+	}
+
+	visible := make([]database.Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		if !hidden[chirp.UserID] {
+			visible = append(visible, chirp)
+		}
+	}
+
+	return visible
+}
+
+// optionalViewer is the user behind the bearer token of an endpoint that
+// doesn't require one, uuid.Nil when there is no valid token
+func (cfg *apiConfig) optionalViewer(r *http.Request) uuid.UUID {
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		return uuid.Nil
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		return uuid.Nil
+	}
+
+	return userID
+}
+
+// blockHandler blocks a user. Blocking ends follows in both directions and
+// hides both users' chirps and profiles from each other. Blocking twice is a
+// no-op.
+func (cfg *apiConfig) blockHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	if blockedID == userID {
+		err = marshalHelper(w, errResponse{Error: "You can't block yourself"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("block user: %v", err)
+		}
+		return
+	}
+
+	follows := []followEvent{
+		{FollowerID: userID, FolloweeID: blockedID},
+		{FollowerID: blockedID, FolloweeID: userID},
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		_, err := q.BlockUser(r.Context(), database.BlockUserParams{
+			BlockerID: userID,
+			BlockedID: blockedID,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, follow := range follows {
+			err = q.UnfollowUser(r.Context(), database.UnfollowUserParams(follow))
+
+			if err != nil {
+				return err
+			}
+
+			if !cfg.timelineFanout {
+				continue
+			}
+
+			err = q.RemoveAuthorFromTimeline(r.Context(), database.RemoveAuthorFromTimelineParams{
+				UserID:   follow.FollowerID,
+				AuthorID: follow.FolloweeID,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	//foreign_key_violation, the blocked user doesn't exist
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to block user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//websocket clients of either user drop the other from their timeline
+	//and stop seeing their chirps
+	for _, follow := range follows {
+		cfg.publish(realtimeFollowDeleted, follow.FollowerID, follow)
+		cfg.publish(realtimeHiddenChanged, follow.FollowerID, nil)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unblockHandler lifts a block, follows it ended aren't restored
+func (cfg *apiConfig) unblockHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	err = cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to unblock user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cfg.publish(realtimeHiddenChanged, userID, nil)
+	cfg.publish(realtimeHiddenChanged, blockedID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// muteHandler mutes a user, hiding their chirps from the muter's timelines
+// and their actions from the muter's notifications. The muted user isn't
+// affected and can't tell. Muting twice is a no-op.
+func (cfg *apiConfig) muteHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	if mutedID == userID {
+		err = marshalHelper(w, errResponse{Error: "You can't mute yourself"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("mute user: %v", err)
+		}
+		return
+	}
+
+	_, err = cfg.db.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+
+	//foreign_key_violation, the muted user doesn't exist
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to mute user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cfg.publish(realtimeHiddenChanged, userID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unmuteHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	err = cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to unmute user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cfg.publish(realtimeHiddenChanged, userID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) blocksHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listRelationships(w, r, func(ctx context.Context, arg database.GetBlocksParams) ([]relationshipResponse, error) {
+		blocks, err := cfg.db.GetBlocks(ctx, arg)
+
+		res := make([]relationshipResponse, 0, len(blocks))
+		for _, block := range blocks {
+			res = append(res, relationshipResponse{UserID: block.BlockedID, CreatedAt: block.CreatedAt})
+		}
+
+		return res, err
+	})
+}
+
+func (cfg *apiConfig) mutesHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listRelationships(w, r, func(ctx context.Context, arg database.GetBlocksParams) ([]relationshipResponse, error) {
+		mutes, err := cfg.db.GetMutes(ctx, database.GetMutesParams(arg))
+
+		res := make([]relationshipResponse, 0, len(mutes))
+		for _, mute := range mutes {
+			res = append(res, relationshipResponse{UserID: mute.MutedID, CreatedAt: mute.CreatedAt})
+		}
+
+		return res, err
+	})
+}
+
+// blocks and mutes are listed the same way, newest first and only to the
+// user that made them
+func (cfg *apiConfig) listRelationships(
+	w http.ResponseWriter,
+	r *http.Request,
+	query func(ctx context.Context, arg database.GetBlocksParams) ([]relationshipResponse, error),
+) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list relationships: %v", err)
+		}
+		return
+	}
+
+	users, err := query(r.Context(), database.GetBlocksParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive relationships: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := relationshipListResponse{
+		Users: users,
+	}
+
+	if len(users) == int(limit) {
+		last := users[len(users)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.UserID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list relationships: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+// bookmarkHandler saves a chirp to the user's bookmarks, bookmarks are only
+// visible to the user. Bookmarking twice is a no-op.
+func (cfg *apiConfig) bookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+
+	if err != nil {
+		log.Printf("no chirp found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = cfg.db.BookmarkChirp(r.Context(), database.BookmarkChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to bookmark chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) removeBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	err = cfg.db.RemoveBookmark(r.Context(), database.RemoveBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to remove bookmark: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bookmarksHandler lists the user's bookmarks, most recently bookmarked
+// first. Bookmarked chirps that were deleted are left out.
+func (cfg *apiConfig) bookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("bookmarks: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bookmarks, err := cfg.db.GetBookmarks(r.Context(), database.GetBookmarksParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive bookmarks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := chirpPageResponse{
+		Chirps: []chirpResponse{},
+	}
+
+	chirps := make([]database.Chirp, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		chirps = append(chirps, bookmark.Chirp)
+	}
+
+	res.Chirps = append(res.Chirps, cfg.chirpsToResponse(r.Context(), hidden.filter(chirps))...)
+
+	//pages follow when chirps were bookmarked, not when they were posted
+	if len(bookmarks) == int(limit) {
+		last := bookmarks[len(bookmarks)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.BookmarkedAt, ID: last.Chirp.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("bookmarks: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpTombstone stands in for a deleted chirp in a thread, keeping its
+// place and replies but nothing it said or who said it
+func chirpTombstone(chirp database.Chirp, counts database.GetChirpsCountsRow) chirpResponse {
+
+	res := chirpResponse{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Kind:      chirp.Kind,
+		Deleted:   true,
+	}
+
+	if chirp.InReplyToID.Valid {
+		res.InReplyToID = &chirp.InReplyToID.UUID
+	}
+
+	res.ReplyCount = counts.ReplyCount
+
+	return res
+}
+
+// restoreChirpHandler undeletes a chirp that hasn't been purged yet
+func (cfg *apiConfig) restoreChirpHandler(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	chirp, err := cfg.db.RestoreChirp(r.Context(), chirpID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = cfg.db.GetChirpWithDeleted(r.Context(), chirpID)
+
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = marshalHelper(w, errResponse{Error: "Chirp isn't deleted"}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("restore chirp: %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to restore chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Chirp %v restored by %v", chirp.ID, staffUser(r.Context()).ID)
+
+	err = marshalHelper(w, cfg.chirpToResponse(r.Context(), chirp), http.StatusOK)
+	if err != nil {
+		fmt.Printf("restore chirp: %v", err)
+	}
+}
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	draftStatusDraft     = "draft"
+	draftStatusScheduled = "scheduled"
+	draftStatusPublished = "published"
+	draftStatusFailed    = "failed"
+	draftStatusCanceled  = "canceled"
+)
+
+const (
+	//how far ahead chirps can be scheduled
+	maxScheduleAhead = 365 * 24 * time.Hour
+	//unscheduled drafts aren't validated, this keeps them reasonably small
+	maxDraftBodyBytes = 4096
+	//how long the scheduler holds a draft while publishing it
+	draftPublishLease = time.Minute
+	draftBatchSize    = 20
+)
+
+// errDraftChanged is returned when a draft was canceled or published while
+// it was being published
+var errDraftChanged = errors.New("draft changed while publishing")
+
+// content is posted as if it were sent to POST /api/chirps
+type draftParams struct {
+	Content   makeChirpParams `json:"content"`
+	PublishAt *time.Time      `json:"publish_at"`
+}
+
+type draftResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	Status    string          `json:"status"`
+	Content   makeChirpParams `json:"content"`
+	PublishAt *time.Time      `json:"publish_at,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	ChirpID   *uuid.UUID      `json:"chirp_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type draftListResponse struct {
+	Drafts     []draftResponse `json:"drafts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func draftToResponse(draft database.Draft) draftResponse {
+
+	res := draftResponse{
+		ID:        draft.ID,
+		Status:    draft.Status,
+		Error:     draft.Error,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+	}
+
+	if err := json.Unmarshal(draft.Content, &res.Content); err != nil {
+		log.Printf("Failed to decode draft %v: %v", draft.ID, err)
+	}
+
+	if draft.PublishAt.Valid {
+		res.PublishAt = &draft.PublishAt.Time
+	}
+
+	if draft.ChirpID.Valid {
+		res.ChirpID = &draft.ChirpID.UUID
+	}
+
+	return res
+}
+
+// checkDraft validates a draft before it is saved. Scheduled drafts are
+// validated like a new chirp up front, so mistakes surface now instead of at
+// publish time; plain drafts can be saved half written.
+func (cfg *apiConfig) checkDraft(ctx context.Context, user database.User, request draftParams) error {
+
+	if len(request.Content.Body) > maxDraftBodyBytes {
+		return &chirpError{status: http.StatusBadRequest, message: fmt.Sprintf("Drafts are limited to %d bytes", maxDraftBodyBytes)}
+	}
+
+	if request.PublishAt == nil {
+		return nil
+	}
+
+	if !cfg.entitlementsFor(user).ScheduledChirps {
+		return &chirpError{status: http.StatusForbidden, message: "Scheduling chirps requires Chirpy Red"}
+	}
+
+	now := time.Now()
+
+	if request.PublishAt.Before(now) || request.PublishAt.After(now.Add(maxScheduleAhead)) {
+		return &chirpError{status: http.StatusBadRequest, message: "publish_at must be in the next 365 days"}
+	}
+
+	_, err := cfg.prepareChirp(ctx, user, request.Content)
+
+	return err
+}
+
+func draftStatusFor(request draftParams) (string, sql.NullTime) {
+
+	if request.PublishAt == nil {
+		return draftStatusDraft, sql.NullTime{}
+	}
+
+	return draftStatusScheduled, sql.NullTime{Time: request.PublishAt.UTC(), Valid: true}
+}
+
+// markDraftPublished links a draft to its chirp in the transaction creating
+// the chirp, so a draft is never published twice
+func markDraftPublished(draftID uuid.UUID) func(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+
+	return func(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+		marked, err := q.MarkDraftPublished(ctx, database.MarkDraftPublishedParams{
+			ID:      draftID,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if marked == 0 {
+			return errDraftChanged
+		}
+
+		return nil
+	}
+}
+
+// writeChirpError answers with a *chirpError, reporting whether err was one
+func writeChirpError(w http.ResponseWriter, err error, context string) bool {
+
+	var chirpErr *chirpError
+	if !errors.As(err, &chirpErr) {
+		return false
+	}
+
+	if chirpErr.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(chirpErr.retryAfter.Seconds())+1))
+	}
+
+	err = marshalHelper(w, errResponse{Error: chirpErr.message}, chirpErr.status)
+	if err != nil {
+		fmt.Printf("%s: %v", context, err)
+	}
+
+	return true
+}
+
+func (cfg *apiConfig) createDraftHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to load user: %v", err)
+		return
+	}
+
+	var request draftParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Invalid draft: %v", err)}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("create draft: %v", err)
+		}
+		return
+	}
+
+	err = cfg.checkDraft(r.Context(), user, request)
+
+	if writeChirpError(w, err, "create draft") {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to check draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	content, err := json.Marshal(request.Content)
+
+	if err != nil {
+		log.Printf("Failed to encode draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status, publishAt := draftStatusFor(request)
+
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:    userID,
+		Content:   content,
+		Status:    status,
+		PublishAt: publishAt,
+	})
+
+	if err != nil {
+		log.Printf("Failed to create draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, draftToResponse(draft), http.StatusCreated)
+	if err != nil {
+		fmt.Printf("create draft: %v", err)
+	}
+}
+
+// listDraftsHandler lists unpublished drafts by default, ?status= takes a
+// comma separated list of statuses
+func (cfg *apiConfig) listDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list drafts: %v", err)
+		}
+		return
+	}
+
+	statuses := []string{draftStatusDraft, draftStatusScheduled, draftStatusFailed}
+
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		statuses = strings.Split(raw, ",")
+	}
+
+	drafts, err := cfg.db.ListDrafts(r.Context(), database.ListDraftsParams{
+		UserID:          userID,
+		Statuses:        statuses,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive drafts: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := draftListResponse{
+		Drafts: []draftResponse{},
+	}
+
+	for _, draft := range drafts {
+		res.Drafts = append(res.Drafts, draftToResponse(draft))
+	}
+
+	if len(drafts) == int(limit) {
+		last := drafts[len(drafts)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list drafts: %v", err)
+	}
+}
+
+func (cfg *apiConfig) getDraftHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+
+	if err != nil {
+		log.Printf("Failed to parse draft ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+
+	if err != nil {
+		log.Printf("no draft found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = marshalHelper(w, draftToResponse(draft), http.StatusOK)
+	if err != nil {
+		fmt.Printf("get draft: %v", err)
+	}
+}
+
+// updateDraftHandler replaces a draft's content and schedule. Leaving out
+// publish_at turns a scheduled chirp back into a draft, and a failed one can
+// be fixed and scheduled again.
+func (cfg *apiConfig) updateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+
+	if err != nil {
+		log.Printf("Failed to parse draft ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to load user: %v", err)
+		return
+	}
+
+	var request draftParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Invalid draft: %v", err)}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("update draft: %v", err)
+		}
+		return
+	}
+
+	err = cfg.checkDraft(r.Context(), user, request)
+
+	if writeChirpError(w, err, "update draft") {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to check draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	content, err := json.Marshal(request.Content)
+
+	if err != nil {
+		log.Printf("Failed to encode draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status, publishAt := draftStatusFor(request)
+
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:        draftID,
+		UserID:    userID,
+		Content:   content,
+		Status:    status,
+		PublishAt: publishAt,
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.draftNotChangeable(w, r, draftID, userID)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to update draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, draftToResponse(draft), http.StatusOK)
+	if err != nil {
+		fmt.Printf("update draft: %v", err)
+	}
+}
+
+// cancelDraftHandler cancels a draft or scheduled chirp, it stays listed
+// under ?status=canceled
+func (cfg *apiConfig) cancelDraftHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+
+	if err != nil {
+		log.Printf("Failed to parse draft ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	_, err = cfg.db.CancelDraft(r.Context(), database.CancelDraftParams{ID: draftID, UserID: userID})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.draftNotChangeable(w, r, draftID, userID)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to cancel draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishDraftHandler publishes a draft right away
+func (cfg *apiConfig) publishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+
+	if err != nil {
+		log.Printf("Failed to parse draft ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to load user: %v", err)
+		return
+	}
+
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+
+	if err != nil {
+		log.Printf("no draft found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var content makeChirpParams
+
+	if err := json.Unmarshal(draft.Content, &content); err != nil {
+		log.Printf("Failed to decode draft %v: %v", draft.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res, err := cfg.createChirp(r.Context(), user, content, markDraftPublished(draft.ID))
+
+	if writeChirpError(w, err, "publish draft") {
+		return
+	}
+
+	if errors.Is(err, errDraftChanged) {
+		cfg.draftNotChangeable(w, r, draftID, userID)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to publish draft: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, res, http.StatusCreated)
+	if err != nil {
+		fmt.Printf("publish draft: %v", err)
+	}
+}
+
+// draftNotChangeable answers 404 for drafts that don't exist and 409 for
+// ones that are published, canceled or being published
+func (cfg *apiConfig) draftNotChangeable(w http.ResponseWriter, r *http.Request, draftID, userID uuid.UUID) {
+
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	message := "Draft is being published"
+	if draft.Status == draftStatusPublished || draft.Status == draftStatusCanceled {
+		message = "Draft is already " + draft.Status
+	}
+
+	err = marshalHelper(w, errResponse{Error: message}, http.StatusConflict)
+	if err != nil {
+		fmt.Printf("change draft: %v", err)
+	}
+}
+
+// publishScheduledChirps publishes due drafts every interval until ctx is done
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cfg.publishDueDrafts(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDueDrafts publishes through createChirp, like POST /api/chirps.
+// Chirps that became invalid since they were scheduled are marked failed
+// with the reason, rate limited ones wait until the limit resets and
+// anything else is retried once the lease runs out.
+func (cfg *apiConfig) publishDueDrafts(ctx context.Context) {
+
+	now := time.Now().UTC()
+
+	drafts, err := cfg.db.ClaimDueDrafts(ctx, database.ClaimDueDraftsParams{
+		LockedUntil: now.Add(draftPublishLease),
+		Now:         now,
+		BatchSize:   draftBatchSize,
+	})
+
+	if err != nil {
+		log.Printf("Failed to claim scheduled chirps: %v", err)
+		return
+	}
+
+	for _, draft := range drafts {
+		err = cfg.publishDraft(ctx, draft)
+
+		var chirpErr *chirpError
+
+		switch {
+		case err == nil, errors.Is(err, errDraftChanged):
+
+		case errors.As(err, &chirpErr) && chirpErr.retryAfter > 0:
+			err = cfg.db.DelayDraft(ctx, database.DelayDraftParams{
+				ID:          draft.ID,
+				LockedUntil: sql.NullTime{Time: time.Now().UTC().Add(chirpErr.retryAfter), Valid: true},
+			})
+
+			if err != nil {
+				log.Printf("Failed to delay scheduled chirp %v: %v", draft.ID, err)
+			}
+
+		case errors.As(err, &chirpErr):
+			err = cfg.db.FailDraft(ctx, database.FailDraftParams{ID: draft.ID, Error: chirpErr.message})
+
+			if err != nil {
+				log.Printf("Failed to mark scheduled chirp %v failed: %v", draft.ID, err)
+			}
+
+		default:
+			log.Printf("Failed to publish scheduled chirp %v: %v", draft.ID, err)
+		}
+	}
+}
+
+func (cfg *apiConfig) publishDraft(ctx context.Context, draft database.Draft) error {
+
+	user, err := cfg.db.GetUserByID(ctx, draft.UserID)
+
+	if err != nil {
+		return err
+	}
+
+	//the membership may have lapsed since the chirp was scheduled
+	if !cfg.entitlementsFor(user).ScheduledChirps {
+		return &chirpError{status: http.StatusForbidden, message: "Scheduling chirps requires Chirpy Red"}
+	}
+
+	var content makeChirpParams
+
+	if err := json.Unmarshal(draft.Content, &content); err != nil {
+		return &chirpError{status: http.StatusBadRequest, message: "Draft content is invalid"}
+	}
+
+	_, err = cfg.createChirp(ctx, user, content, markDraftPublished(draft.ID))
+
+	return err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/chirptext"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/moderation"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+type editChirpParams struct {
+	Body string `json:"body"`
+}
+
+// editChirpHandler replaces the body of the caller's chirp, as long as
+// their plan allows edits and the edit window hasn't closed
+func (cfg *apiConfig) editChirpHandler(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var request editChirpParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Error, unable to edit chirp: %v", err)}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("edit chirp: %v", err)
+		}
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+
+	if err != nil {
+		log.Printf("no chirp found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if chirp.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	suspension, suspended, err := cfg.activeSuspension(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to check suspension: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if suspended {
+		writeSuspended(w, suspension, "edit chirp")
+		return
+	}
+
+	plan := cfg.entitlementsFor(user)
+
+	var reason string
+	switch {
+	case chirp.Kind == chirpKindRechirp:
+		reason = "Rechirps can't be edited"
+	case plan.EditWindow == 0:
+		reason = "Editing chirps requires Chirpy Red"
+	case !plan.CanEdit(chirp.CreatedAt, time.Now()):
+		reason = fmt.Sprintf("Chirps can only be edited for %v after posting", plan.EditWindow)
+	}
+
+	if reason != "" {
+		err = marshalHelper(w, errResponse{Error: reason}, http.StatusForbidden)
+		if err != nil {
+			fmt.Printf("edit chirp: %v", err)
+		}
+		return
+	}
+
+	body := chirptext.Normalize(request.Body)
+
+	err = cfg.checkChirpLength(plan, body)
+
+	if err == nil && body == "" {
+		err = fmt.Errorf("Chirp body can't be empty")
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("edit chirp: %v", err)
+		}
+		return
+	}
+
+	verdict := cfg.moderation.Check(body)
+
+	if verdict.Action == moderation.ActionReject {
+		logRejection(userID, verdict)
+
+		err = marshalHelper(w, errResponse{Error: "Chirp rejected by moderation"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("edit chirp: %v", err)
+		}
+		return
+	}
+
+	chirp, err = cfg.db.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:     chirp.ID,
+		UserID: userID,
+		Body:   verdict.Body,
+	})
+
+	if err != nil {
+		log.Printf("Failed to edit chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if verdict.Action == moderation.ActionFlag {
+		cfg.flagChirp(r.Context(), chirp, verdict)
+	}
+
+	//mentions and hashtags are re-indexed from the new body
+	err = cfg.db.DeleteChirpEntities(r.Context(), chirp.ID)
+
+	if err != nil {
+		log.Printf("Failed to clear entities of chirp %v: %v", chirp.ID, err)
+	}
+
+	cfg.indexChirpEntities(r.Context(), chirp)
+
+	//links in the new body are fetched again, the page may have changed
+	//since the previous preview
+	if cfg.linkPreviews != nil {
+		for _, url := range linkPreviewURLs(chirp) {
+			cfg.linkPreviews.Enqueue(url)
+		}
+	}
+
+	res := cfg.chirpToResponse(r.Context(), chirp)
+
+	cfg.emitEvent(r.Context(), webhooks.ChirpUpdated, chirp.UserID, res)
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("edit chirp: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/chirptext"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+// offsets are in unicode code points, end is exclusive
+type entitiesResponse struct {
+	Mentions []mentionEntity `json:"mentions"`
+	Hashtags []hashtagEntity `json:"hashtags"`
+}
+
+type mentionEntity struct {
+	Handle string    `json:"handle"`
+	UserID uuid.UUID `json:"user_id"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+}
+
+type hashtagEntity struct {
+	Tag   string `json:"tag"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// indexChirpEntities stores the mentions and hashtags of a new chirp, a
+// failure only leaves the chirp out of the mention and hashtag lists
+func (cfg *apiConfig) indexChirpEntities(ctx context.Context, chirp database.Chirp) {
+
+	entities := chirptext.Parse(chirp.Body)
+
+	if len(entities.Mentions) > 0 {
+		err := cfg.db.AddChirpMentions(ctx, database.AddChirpMentionsParams{
+			ChirpID:   chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			Handles:   entities.MentionedHandles(),
+		})
+
+		if err != nil {
+			log.Printf("Failed to index mentions of chirp %v: %v", chirp.ID, err)
+		}
+	}
+
+	if len(entities.Hashtags) > 0 {
+		err := cfg.db.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+			ChirpID:   chirp.ID,
+			Tags:      entities.Tags(),
+			CreatedAt: chirp.CreatedAt,
+		})
+
+		if err != nil {
+			log.Printf("Failed to index hashtags of chirp %v: %v", chirp.ID, err)
+		}
+	}
+}
+
+// chirpEntities re-parses the body for offsets, mentions are only returned
+// when they resolved to a user at the time the chirp was created. mentioned
+// maps the lowercased handles the chirp mentioned to their users.
+func chirpEntities(chirp database.Chirp, mentioned map[string]uuid.UUID) entitiesResponse {
+
+	res := entitiesResponse{
+		Mentions: []mentionEntity{},
+		Hashtags: []hashtagEntity{},
+	}
+
+	entities := chirptext.Parse(chirp.Body)
+
+	for _, hashtag := range entities.Hashtags {
+		res.Hashtags = append(res.Hashtags, hashtagEntity{
+			Tag:   hashtag.Tag,
+			Start: hashtag.Start,
+			End:   hashtag.End,
+		})
+	}
+
+	for _, mention := range entities.Mentions {
+		userID, ok := mentioned[strings.ToLower(mention.Handle)]
+		if !ok {
+			continue
+		}
+
+		res.Mentions = append(res.Mentions, mentionEntity{
+			Handle: mention.Handle,
+			UserID: userID,
+			Start:  mention.Start,
+			End:    mention.End,
+		})
+	}
+
+	return res
+}
+
+func (cfg *apiConfig) hashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+
+	tag := chirptext.NormalizeHashtag(r.PathValue("tag"))
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("hashtag chirps: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), cfg.optionalViewer(r))
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirps, err := cfg.db.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:             tag,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive chirps for #%v: %v", tag, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.chirpPage(r.Context(), chirps, limit, hidden), http.StatusOK)
+	if err != nil {
+		fmt.Printf("hashtag chirps: %v", err)
+	}
+}
+
+func (cfg *apiConfig) mentionsHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("mentions: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirps, err := cfg.db.GetMentions(r.Context(), database.GetMentionsParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive mentions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.chirpPage(r.Context(), chirps, limit, hidden), http.StatusOK)
+	if err != nil {
+		fmt.Printf("mentions: %v", err)
+	}
+}
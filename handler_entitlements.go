@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/entitlements"
+)
+
+type entitlementsResponse struct {
+	Plan              entitlements.Plan `json:"plan"`
+	MaxChirpLength    int               `json:"max_chirp_length"`
+	EditWindowSeconds int               `json:"edit_window_seconds"`
+	ScheduledChirps   bool              `json:"scheduled_chirps"`
+	ChirpsPerHour     int               `json:"chirps_per_hour"`
+	MediaPerChirp     int               `json:"media_per_chirp"`
+}
+
+// entitlementsFor looks up what the user's plan allows, handlers should
+// check these rather than IsChirpRed
+func (cfg *apiConfig) entitlementsFor(user database.User) entitlements.Entitlements {
+	return cfg.plans.For(entitlements.PlanOf(user.IsChirpRed))
+}
+
+func (cfg *apiConfig) entitlementsHandler(w http.ResponseWriter, r *http.Request) {
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	e := cfg.entitlementsFor(user)
+
+	err = marshalHelper(w, entitlementsResponse{
+		Plan:              e.Plan,
+		MaxChirpLength:    e.MaxChirpLength,
+		EditWindowSeconds: int(e.EditWindow.Seconds()),
+		ScheduledChirps:   e.ScheduledChirps,
+		ChirpsPerHour:     e.ChirpsPerHour,
+		MediaPerChirp:     e.MediaPerChirp,
+	}, http.StatusOK)
+	if err != nil {
+		log.Printf("entitlements: %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const (
+	minChirpTTL = time.Minute
+	maxChirpTTL = 30 * 24 * time.Hour
+	//expired chirps deleted per transaction, their replies come on top
+	reaperBatchSize = 100
+)
+
+// reaperMetrics counts what the reaper deleted since the server started
+type reaperMetrics struct {
+	runs           atomic.Int64
+	failures       atomic.Int64
+	chirpsDeleted  atomic.Int64
+	repliesDeleted atomic.Int64
+	chirpsPurged   atomic.Int64
+	mediaDeleted   atomic.Int64
+	lastRunAt      atomic.Int64
+}
+
+type reaperMetricsResponse struct {
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	ChirpsDeleted  int64      `json:"chirps_deleted"`
+	RepliesDeleted int64      `json:"replies_deleted"`
+	ChirpsPurged   int64      `json:"chirps_purged"`
+	MediaDeleted   int64      `json:"media_deleted"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+}
+
+// chirpExpiry turns expires_in, in seconds, into when the chirp expires
+func chirpExpiry(expiresIn int) (sql.NullTime, error) {
+
+	if expiresIn == 0 {
+		return sql.NullTime{}, nil
+	}
+
+	ttl := time.Duration(expiresIn) * time.Second
+
+	if ttl < minChirpTTL || ttl > maxChirpTTL {
+		return sql.NullTime{}, fmt.Errorf("expires_in must be between %d and %d seconds", int(minChirpTTL.Seconds()), int(maxChirpTTL.Seconds()))
+	}
+
+	return sql.NullTime{Time: time.Now().UTC().Add(ttl), Valid: true}, nil
+}
+
+// reapExpiredChirps deletes expired chirps, and purges deleted chirps past
+// their retention, every interval until ctx is done. Both are hidden already,
+// this only frees the rows.
+func (cfg *apiConfig) reapExpiredChirps(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		//keep going while there's a backlog
+		for {
+			expired, err := cfg.reapChirps(ctx, time.Now().UTC())
+
+			if err != nil {
+				cfg.reaper.failures.Add(1)
+				log.Printf("Failed to delete expired chirps: %v", err)
+			}
+
+			if err != nil || expired < reaperBatchSize {
+				break
+			}
+		}
+
+		for {
+			purged, err := cfg.purgeDeletedChirps(ctx, time.Now().UTC().Add(-cfg.chirpRetention))
+
+			if err != nil {
+				cfg.reaper.failures.Add(1)
+				log.Printf("Failed to purge deleted chirps: %v", err)
+			}
+
+			if err != nil || purged < reaperBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapChirps hard-deletes a batch of chirps expired at now in one
+// transaction, along with every reply below them, their likes and their
+// media. It returns how many expired chirps were deleted.
+func (cfg *apiConfig) reapChirps(ctx context.Context, now time.Time) (int, error) {
+
+	cfg.reaper.runs.Add(1)
+	cfg.reaper.lastRunAt.Store(now.Unix())
+
+	var doomed []database.GetExpiredChirpThreadsRow
+	var files []database.MediaFile
+	expired := 0
+
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		doomed = nil
+
+		rows, err := q.GetExpiredChirpThreads(ctx, database.GetExpiredChirpThreadsParams{
+			Now:       now,
+			BatchSize: reaperBatchSize,
+		})
+
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		//a reply that expired itself comes back twice
+		seen := map[uuid.UUID]int{}
+		for _, row := range rows {
+			if i, ok := seen[row.ID]; ok {
+				doomed[i].Expired = doomed[i].Expired || row.Expired
+				continue
+			}
+			seen[row.ID] = len(doomed)
+			doomed = append(doomed, row)
+		}
+
+		ids := make([]uuid.UUID, 0, len(doomed))
+		for _, chirp := range doomed {
+			ids = append(ids, chirp.ID)
+		}
+
+		files, err = hardDeleteChirps(ctx, q, ids)
+
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	cfg.deleteMediaBlobs(ctx, files)
+
+	for _, chirp := range doomed {
+		if chirp.Expired {
+			expired++
+		}
+
+		cfg.emitEvent(ctx, webhooks.ChirpDeleted, chirp.UserID, chirpDeletedEvent{ID: chirp.ID, UserID: chirp.UserID})
+	}
+
+	cfg.reaper.chirpsDeleted.Add(int64(expired))
+	cfg.reaper.repliesDeleted.Add(int64(len(doomed) - expired))
+	cfg.reaper.mediaDeleted.Add(int64(len(files)))
+
+	if len(doomed) > 0 {
+		log.Printf("Deleted %d expired chirps, %d replies and %d media", expired, len(doomed)-expired, len(files))
+	}
+
+	return expired, nil
+}
+
+// purgeDeletedChirps hard-deletes a batch of chirps deleted before
+// deletedBefore, and returns how many. Their replies stay, no longer
+// pointing at them.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context, deletedBefore time.Time) (int, error) {
+
+	var ids []uuid.UUID
+	var files []database.MediaFile
+
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+
+		ids, err = q.GetPurgeableChirps(ctx, database.GetPurgeableChirpsParams{
+			DeletedBefore: deletedBefore,
+			BatchSize:     reaperBatchSize,
+		})
+
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		files, err = hardDeleteChirps(ctx, q, ids)
+
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	cfg.deleteMediaBlobs(ctx, files)
+
+	cfg.reaper.chirpsPurged.Add(int64(len(ids)))
+	cfg.reaper.mediaDeleted.Add(int64(len(files)))
+
+	if len(ids) > 0 {
+		log.Printf("Purged %d deleted chirps and %d media", len(ids), len(files))
+	}
+
+	return len(ids), nil
+}
+
+// hardDeleteChirps deletes chirps for good, with their likes, attachments,
+// entities and polls, and the media attached to them. It returns the deleted
+// media, whose blobs are removed once the transaction commits.
+func hardDeleteChirps(ctx context.Context, q *database.Queries, ids []uuid.UUID) ([]database.MediaFile, error) {
+
+	files, err := q.GetChirpsMediaFiles(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = q.DeleteChirps(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fileIDs := make([]uuid.UUID, 0, len(files))
+	for _, file := range files {
+		fileIDs = append(fileIDs, file.ID)
+	}
+
+	return files, q.DeleteMediaFiles(ctx, fileIDs)
+}
+
+func (cfg *apiConfig) reaperMetricsHandler(w http.ResponseWriter, r *http.Request) {
+
+	res := reaperMetricsResponse{
+		Runs:           cfg.reaper.runs.Load(),
+		Failures:       cfg.reaper.failures.Load(),
+		ChirpsDeleted:  cfg.reaper.chirpsDeleted.Load(),
+		RepliesDeleted: cfg.reaper.repliesDeleted.Load(),
+		ChirpsPurged:   cfg.reaper.chirpsPurged.Load(),
+		MediaDeleted:   cfg.reaper.mediaDeleted.Load(),
+	}
+
+	if last := cfg.reaper.lastRunAt.Load(); last > 0 {
+		lastRunAt := time.Unix(last, 0).UTC()
+		res.LastRunAt = &lastRunAt
+	}
+
+	err := marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("reaper metrics: %v", err)
+	}
+}
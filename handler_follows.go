@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/notify"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type followResponse struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followListResponse struct {
+	Users      []followResponse `json:"users"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	if followeeID == userID {
+		err = marshalHelper(w, errResponse{Error: "You can't follow yourself"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("follow user: %v", err)
+		}
+		return
+	}
+
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		UserID:  userID,
+		OtherID: followeeID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to check blocks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if blocked {
+		err = marshalHelper(w, errResponse{Error: "You can't follow this user"}, http.StatusForbidden)
+		if err != nil {
+			fmt.Printf("follow user: %v", err)
+		}
+		return
+	}
+
+	followed, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+
+	//foreign_key_violation, the followed user doesn't exist
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to follow user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if followed > 0 {
+		event := followEvent{FollowerID: userID, FolloweeID: followeeID}
+
+		cfg.publish(realtimeFollowCreated, userID, event)
+		cfg.notify(r.Context(), followeeID, userID, notify.TypeFollow, uuid.NullUUID{})
+	}
+
+	if cfg.timelineFanout {
+		err = cfg.db.BackfillTimeline(r.Context(), database.BackfillTimelineParams{
+			UserID:   userID,
+			AuthorID: followeeID,
+		})
+
+		if err != nil {
+			log.Printf("Failed to backfill timeline: %v", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to unfollow user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cfg.publish(realtimeFollowDeleted, userID, followEvent{FollowerID: userID, FolloweeID: followeeID})
+
+	if cfg.timelineFanout {
+		err = cfg.db.RemoveAuthorFromTimeline(r.Context(), database.RemoveAuthorFromTimelineParams{
+			UserID:   userID,
+			AuthorID: followeeID,
+		})
+
+		if err != nil {
+			log.Printf("Failed to clean up timeline: %v", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) followersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, cfg.db.GetFollowers, func(f database.Follow) uuid.UUID {
+		return f.FollowerID
+	})
+}
+
+func (cfg *apiConfig) followingHandler(w http.ResponseWriter, r *http.Request) {
+	getFollowing := func(ctx context.Context, arg database.GetFollowersParams) ([]database.Follow, error) {
+		return cfg.db.GetFollowing(ctx, database.GetFollowingParams(arg))
+	}
+
+	cfg.listFollows(w, r, getFollowing, func(f database.Follow) uuid.UUID {
+		return f.FolloweeID
+	})
+}
+
+// followers and following only differ in which side of the follow is listed
+func (cfg *apiConfig) listFollows(
+	w http.ResponseWriter,
+	r *http.Request,
+	query func(ctx context.Context, arg database.GetFollowersParams) ([]database.Follow, error),
+	other func(database.Follow) uuid.UUID,
+) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list follows: %v", err)
+		}
+		return
+	}
+
+	follows, err := query(r.Context(), database.GetFollowersParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive follows: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := followListResponse{
+		Users: []followResponse{},
+	}
+
+	for _, follow := range follows {
+		res.Users = append(res.Users, followResponse{
+			UserID:     other(follow),
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+
+	if len(follows) == int(limit) {
+		last := follows[len(follows)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: other(last)}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list follows: %v", err)
+	}
+}
+
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("timeline: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var chirps []database.Chirp
+
+	if cfg.timelineFanout {
+		chirps, err = cfg.db.GetMaterializedTimeline(r.Context(), database.GetMaterializedTimelineParams{
+			UserID:          userID,
+			BeforeCreatedAt: pos.CreatedAt,
+			BeforeID:        pos.ID,
+			PageSize:        limit,
+		})
+	} else {
+		chirps, err = cfg.db.GetTimeline(r.Context(), database.GetTimelineParams{
+			UserID:          userID,
+			BeforeCreatedAt: pos.CreatedAt,
+			BeforeID:        pos.ID,
+			PageSize:        limit,
+		})
+	}
+
+	if err != nil {
+		log.Printf("Failed to retreive timeline: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.chirpPage(r.Context(), chirps, limit, hidden), http.StatusOK)
+	if err != nil {
+		fmt.Printf("timeline: %v", err)
+	}
+}
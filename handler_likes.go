@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/notify"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) likeHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+
+	if err != nil {
+		log.Printf("no chirp found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	liked, err := cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to like chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if liked > 0 {
+		cfg.notify(r.Context(), chirp.UserID, userID, notify.TypeLike, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unlikeHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to unlike chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// repliesHandler lists the direct replies to a chirp, newest first
+func (cfg *apiConfig) repliesHandler(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list replies: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), cfg.optionalViewer(r))
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	replies, err := cfg.db.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+		ChirpID:         chirpID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive replies: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.chirpPage(r.Context(), replies, limit, hidden), http.StatusOK)
+	if err != nil {
+		fmt.Printf("list replies: %v", err)
+	}
+}
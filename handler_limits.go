@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/JonMunkholm/server/internal/chirptext"
+	"github.com/JonMunkholm/server/internal/entitlements"
+)
+
+type chirpLimitsResponse struct {
+	Limits    map[entitlements.Plan]int `json:"limits"`
+	URLWeight int                       `json:"url_weight"`
+	Counting  []string                  `json:"counting"`
+}
+
+// loadChirpLimits applies CHIRP_LIMIT_FREE, CHIRP_LIMIT_CHIRPY_RED and
+// CHIRP_URL_WEIGHT on top of the plan defaults
+func loadChirpLimits(plans entitlements.Catalog) (int, error) {
+
+	urlWeight := chirptext.DefaultURLWeight
+
+	settings := []struct {
+		env  string
+		plan entitlements.Plan
+	}{
+		{env: "CHIRP_LIMIT_FREE", plan: entitlements.PlanFree},
+		{env: "CHIRP_LIMIT_CHIRPY_RED", plan: entitlements.PlanChirpyRed},
+		{env: "CHIRP_URL_WEIGHT"},
+	}
+
+	for _, setting := range settings {
+		raw := os.Getenv(setting.env)
+
+		if raw == "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(raw)
+
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("%v must be a positive number, got %q", setting.env, raw)
+		}
+
+		if setting.plan == "" {
+			urlWeight = value
+			continue
+		}
+
+		e := plans[setting.plan]
+		e.MaxChirpLength = value
+		plans[setting.plan] = e
+	}
+
+	return urlWeight, nil
+}
+
+// checkChirpLength measures a normalized body and fails when it is over
+// the limit of the user's plan
+func (cfg *apiConfig) checkChirpLength(e entitlements.Entitlements, body string) error {
+
+	length := chirptext.Length(body, cfg.urlWeight)
+
+	if length > e.MaxChirpLength {
+		return fmt.Errorf("Chirp is longer than %d characters (%d)", e.MaxChirpLength, length)
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) chirpLimitsHandler(w http.ResponseWriter, r *http.Request) {
+
+	res := chirpLimitsResponse{
+		Limits:    map[entitlements.Plan]int{},
+		URLWeight: cfg.urlWeight,
+		Counting: []string{
+			"the body is NFC normalized and trimmed, runs of spaces and tabs count as one space and runs of line breaks as at most two",
+			"each grapheme cluster counts as one character, so emoji with modifiers and accented letters count once",
+			"each http(s):// or www. link counts as url_weight characters whatever its length",
+		},
+	}
+
+	for plan, e := range cfg.plans {
+		res.Limits[plan] = e.MaxChirpLength
+	}
+
+	err := marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		log.Printf("chirp limits: %v", err)
+	}
+}
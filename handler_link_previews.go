@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/chirptext"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/linkpreview"
+)
+
+const (
+	//previews are refetched once they are older than this
+	linkPreviewTTL = 24 * time.Hour
+	//failed fetches are retried sooner
+	linkPreviewRetryAfter = time.Hour
+	linkPreviewQueueSize  = 256
+)
+
+type linkPreviewResponse struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// linkPreviewer fetches previews in the background. A url is only queued
+// once at a time, and when the queue is full it is picked up again the next
+// time a chirp linking to it is read.
+type linkPreviewer struct {
+	fetcher *linkpreview.Fetcher
+	db      *database.Queries
+	queue   chan string
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func newLinkPreviewer(fetcher *linkpreview.Fetcher, db *database.Queries) *linkPreviewer {
+	return &linkPreviewer{
+		fetcher: fetcher,
+		db:      db,
+		queue:   make(chan string, linkPreviewQueueSize),
+		pending: map[string]bool{},
+	}
+}
+
+// Run fetches queued urls with the given number of workers until ctx is done
+func (p *linkPreviewer) Run(ctx context.Context, workers int) {
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case url := <-p.queue:
+					p.fetch(ctx, url)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (p *linkPreviewer) Enqueue(url string) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending[url] {
+		return
+	}
+
+	select {
+	case p.queue <- url:
+		p.pending[url] = true
+	default:
+		log.Printf("Link preview queue full, skipping %v", url)
+	}
+}
+
+// fetch stores the preview, or the failure so the url isn't hammered
+func (p *linkPreviewer) fetch(ctx context.Context, url string) {
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, url)
+		p.mu.Unlock()
+	}()
+
+	preview, err := p.fetcher.Fetch(ctx, url)
+
+	params := database.SaveLinkPreviewParams{
+		Url:         url,
+		Status:      "ok",
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageUrl:    preview.Image,
+		SiteName:    preview.SiteName,
+	}
+
+	if err == nil && preview.Empty() {
+		err = linkpreview.ErrNotHTML
+	}
+
+	if err != nil {
+		params.Status = "failed"
+		params.Error = err.Error()
+	}
+
+	err = p.db.SaveLinkPreview(ctx, params)
+
+	if err != nil {
+		log.Printf("Failed to save link preview of %v: %v", url, err)
+	}
+}
+
+// linkPreviewURLs are the normalized urls of the links in a chirp, in the
+// order they appear and without repeats
+func linkPreviewURLs(chirp database.Chirp) []string {
+
+	var urls []string
+	seen := map[string]bool{}
+
+	for _, link := range chirptext.URLs(chirp.Body) {
+		url, err := linkpreview.Normalize(link.Text)
+
+		if err != nil || seen[url] {
+			continue
+		}
+
+		seen[url] = true
+		urls = append(urls, url)
+	}
+
+	return urls
+}
+
+// chirpLinkPreviews returns the previews of the links in a chirp, in the
+// order they appear. Links without a fresh preview in stored are queued for
+// fetching, so a new chirp gets its previews shortly after it is posted.
+func (cfg *apiConfig) chirpLinkPreviews(chirp database.Chirp, stored map[string]database.LinkPreview) []linkPreviewResponse {
+
+	if cfg.linkPreviews == nil {
+		return nil
+	}
+
+	var res []linkPreviewResponse
+
+	for _, url := range linkPreviewURLs(chirp) {
+		row, ok := stored[url]
+
+		ttl := linkPreviewTTL
+		if row.Status != "ok" {
+			ttl = linkPreviewRetryAfter
+		}
+
+		if !ok || time.Since(row.FetchedAt) > ttl {
+			cfg.linkPreviews.Enqueue(url)
+		}
+
+		if ok && row.Status == "ok" {
+			res = append(res, linkPreviewResponse{
+				URL:         row.Url,
+				Title:       row.Title,
+				Description: row.Description,
+				ImageURL:    row.ImageUrl,
+				SiteName:    row.SiteName,
+			})
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,754 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+	maxListsPerUser          = 100
+	maxListMembers           = 500
+)
+
+// errListFull is returned when a list already has maxListMembers members
+var errListFull = errors.New("list is full")
+
+type listParams struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+type listResponse struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+	MemberCount int64     `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type listListResponse struct {
+	Lists      []listResponse `json:"lists"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type listMemberResponse struct {
+	UserID  uuid.UUID `json:"user_id"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type listMembersResponse struct {
+	Users      []listMemberResponse `json:"users"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// checkList trims a list's name and description and checks their length
+func checkList(params listParams) (listParams, error) {
+
+	params.Name = strings.TrimSpace(params.Name)
+	params.Description = strings.TrimSpace(params.Description)
+
+	if params.Name == "" || utf8.RuneCountInString(params.Name) > maxListNameLength {
+		return params, fmt.Errorf("List names are 1 to %d characters", maxListNameLength)
+	}
+
+	if utf8.RuneCountInString(params.Description) > maxListDescriptionLength {
+		return params, fmt.Errorf("List descriptions are limited to %d characters", maxListDescriptionLength)
+	}
+
+	return params, nil
+}
+
+func (cfg *apiConfig) listToResponse(ctx context.Context, list database.List) listResponse {
+
+	members, err := cfg.db.CountListMembers(ctx, list.ID)
+
+	if err != nil {
+		log.Printf("Failed to count members of list %v: %v", list.ID, err)
+	}
+
+	return listResponse{
+		ID:          list.ID,
+		OwnerID:     list.OwnerID,
+		Name:        list.Name,
+		Description: list.Description,
+		Private:     list.Private,
+		MemberCount: members,
+		CreatedAt:   list.CreatedAt,
+		UpdatedAt:   list.UpdatedAt,
+	}
+}
+
+// visibleList returns a list userID may see, private lists of other users
+// are sql.ErrNoRows like lists that don't exist
+func (cfg *apiConfig) visibleList(ctx context.Context, listID, userID uuid.UUID) (database.List, error) {
+
+	list, err := cfg.db.GetList(ctx, listID)
+
+	if err != nil {
+		return database.List{}, err
+	}
+
+	if list.Private && list.OwnerID != userID {
+		return database.List{}, sql.ErrNoRows
+	}
+
+	return list, nil
+}
+
+// ownedList returns a list userID owns, answering 404 or 403 itself when
+// they don't
+func (cfg *apiConfig) ownedList(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.List, bool) {
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+
+	if err != nil {
+		log.Printf("Failed to parse list ID")
+		w.WriteHeader(http.StatusNotFound)
+		return database.List{}, false
+	}
+
+	list, err := cfg.visibleList(r.Context(), listID, userID)
+
+	if err != nil {
+		log.Printf("no list found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return database.List{}, false
+	}
+
+	if list.OwnerID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return database.List{}, false
+	}
+
+	return list, true
+}
+
+func (cfg *apiConfig) createListHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	var request listParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err == nil {
+		request, err = checkList(request)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("create list: %v", err)
+		}
+		return
+	}
+
+	count, err := cfg.db.CountUserLists(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to count lists: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if count >= maxListsPerUser {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("You can have at most %d lists", maxListsPerUser)}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("create list: %v", err)
+		}
+		return
+	}
+
+	list, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		OwnerID:     userID,
+		Name:        request.Name,
+		Description: request.Description,
+		Private:     request.Private,
+	})
+
+	if err != nil {
+		log.Printf("Failed to create list: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.listToResponse(r.Context(), list), http.StatusCreated)
+	if err != nil {
+		fmt.Printf("create list: %v", err)
+	}
+}
+
+// myListsHandler lists the user's own lists, private ones included
+func (cfg *apiConfig) myListsHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	cfg.listUserLists(w, r, userID, true)
+}
+
+// userListsHandler lists another user's public lists
+func (cfg *apiConfig) userListsHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	ownerID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	cfg.listUserLists(w, r, ownerID, ownerID == userID)
+}
+
+func (cfg *apiConfig) listUserLists(w http.ResponseWriter, r *http.Request, ownerID uuid.UUID, includePrivate bool) {
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list lists: %v", err)
+		}
+		return
+	}
+
+	lists, err := cfg.db.GetUserLists(r.Context(), database.GetUserListsParams{
+		OwnerID:         ownerID,
+		IncludePrivate:  includePrivate,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive lists: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := listListResponse{
+		Lists: []listResponse{},
+	}
+
+	for _, list := range lists {
+		res.Lists = append(res.Lists, cfg.listToResponse(r.Context(), list))
+	}
+
+	if len(lists) == int(limit) {
+		last := lists[len(lists)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list lists: %v", err)
+	}
+}
+
+func (cfg *apiConfig) getListHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	listID, err := uuid.Parse(r.PathValue("listID"))
+
+	if err != nil {
+		log.Printf("Failed to parse list ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, err := cfg.visibleList(r.Context(), listID, userID)
+
+	if err != nil {
+		log.Printf("no list found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = marshalHelper(w, cfg.listToResponse(r.Context(), list), http.StatusOK)
+	if err != nil {
+		fmt.Printf("get list: %v", err)
+	}
+}
+
+// updateListHandler replaces a list's name, description and privacy
+func (cfg *apiConfig) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, ok := cfg.ownedList(w, r, userID)
+
+	if !ok {
+		return
+	}
+
+	var request listParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err == nil {
+		request, err = checkList(request)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("update list: %v", err)
+		}
+		return
+	}
+
+	list, err = cfg.db.UpdateList(r.Context(), database.UpdateListParams{
+		ID:          list.ID,
+		OwnerID:     userID,
+		Name:        request.Name,
+		Description: request.Description,
+		Private:     request.Private,
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to update list: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.listToResponse(r.Context(), list), http.StatusOK)
+	if err != nil {
+		fmt.Printf("update list: %v", err)
+	}
+}
+
+func (cfg *apiConfig) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, ok := cfg.ownedList(w, r, userID)
+
+	if !ok {
+		return
+	}
+
+	_, err = cfg.db.DeleteList(r.Context(), database.DeleteListParams{ID: list.ID, OwnerID: userID})
+
+	if err != nil {
+		log.Printf("Failed to delete list: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addListMemberHandler adds an account to a list, adding it twice is a no-op
+func (cfg *apiConfig) addListMemberHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, ok := cfg.ownedList(w, r, userID)
+
+	if !ok {
+		return
+	}
+
+	//blocked users can't be listed by, or list, each other
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		UserID:  userID,
+		OtherID: memberID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to check blocks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if blocked {
+		err = marshalHelper(w, errResponse{Error: "You can't add this user to a list"}, http.StatusForbidden)
+		if err != nil {
+			fmt.Printf("add list member: %v", err)
+		}
+		return
+	}
+
+	//the list stays locked while counting, so concurrent adds can't go
+	//past the cap
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		_, err := q.LockList(r.Context(), list.ID)
+
+		if err != nil {
+			return err
+		}
+
+		members, err := q.CountListMembers(r.Context(), list.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if members >= maxListMembers {
+			return errListFull
+		}
+
+		_, err = q.AddListMember(r.Context(), database.AddListMemberParams{
+			ListID: list.ID,
+			UserID: memberID,
+		})
+
+		return err
+	})
+
+	if errors.Is(err, errListFull) {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Lists can have at most %d members", maxListMembers)}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("add list member: %v", err)
+		}
+		return
+	}
+
+	//the list was deleted meanwhile, or (foreign key violation) the user
+	//doesn't exist
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to add list member: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) removeListMemberHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		log.Printf("Failed to parse user ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, ok := cfg.ownedList(w, r, userID)
+
+	if !ok {
+		return
+	}
+
+	err = cfg.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to remove list member: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listMembersHandler lists a list's members, most recently added first
+func (cfg *apiConfig) listMembersHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	listID, err := uuid.Parse(r.PathValue("listID"))
+
+	if err != nil {
+		log.Printf("Failed to parse list ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, err := cfg.visibleList(r.Context(), listID, userID)
+
+	if err != nil {
+		log.Printf("no list found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list members: %v", err)
+		}
+		return
+	}
+
+	members, err := cfg.db.GetListMembers(r.Context(), database.GetListMembersParams{
+		ListID:          list.ID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive list members: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := listMembersResponse{
+		Users: []listMemberResponse{},
+	}
+
+	for _, member := range members {
+		res.Users = append(res.Users, listMemberResponse{
+			UserID:  member.UserID,
+			AddedAt: member.CreatedAt,
+		})
+	}
+
+	if len(members) == int(limit) {
+		last := members[len(members)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.UserID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list members: %v", err)
+	}
+}
+
+// listTimelineHandler is the timeline of a list's members, newest first
+func (cfg *apiConfig) listTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	listID, err := uuid.Parse(r.PathValue("listID"))
+
+	if err != nil {
+		log.Printf("Failed to parse list ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	list, err := cfg.visibleList(r.Context(), listID, userID)
+
+	if err != nil {
+		log.Printf("no list found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list timeline: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirps, err := cfg.db.GetListTimeline(r.Context(), database.GetListTimelineParams{
+		ListID:          list.ID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive list timeline: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, cfg.chirpPage(r.Context(), chirps, limit, hidden), http.StatusOK)
+	if err != nil {
+		fmt.Printf("list timeline: %v", err)
+	}
+}
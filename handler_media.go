@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/blob"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/media"
+	"github.com/google/uuid"
+)
+
+const defaultMediaMaxBytes = 5 << 20
+
+// maxUnattachedMedia caps the uploads a user has waiting to be attached, so
+// uploads that are never used can't pile up until the reaper gets to them
+const maxUnattachedMedia = 40
+
+// how many unattached uploads the reaper deletes per transaction
+const mediaReaperBatchSize = 100
+
+type mediaResponse struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	SizeBytes    int64     `json:"size_bytes"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// loadMediaConfig reads MEDIA_DIR, where uploads are stored, and
+// MEDIA_MAX_BYTES
+func loadMediaConfig() (*blob.Local, int64, error) {
+
+	dir := os.Getenv("MEDIA_DIR")
+
+	if dir == "" {
+		dir = "media"
+	}
+
+	store, err := blob.NewLocal(dir)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	maxBytes := int64(defaultMediaMaxBytes)
+
+	if raw := os.Getenv("MEDIA_MAX_BYTES"); raw != "" {
+		maxBytes, err = strconv.ParseInt(raw, 10, 64)
+
+		if err != nil || maxBytes < 1 {
+			return nil, 0, fmt.Errorf("invalid MEDIA_MAX_BYTES: %q", raw)
+		}
+	}
+
+	return store, maxBytes, nil
+}
+
+func mediaToResponse(file database.MediaFile) mediaResponse {
+
+	url := "/api/media/" + file.ID.String()
+
+	return mediaResponse{
+		ID:           file.ID,
+		ContentType:  file.ContentType,
+		Width:        file.Width,
+		Height:       file.Height,
+		SizeBytes:    file.SizeBytes,
+		URL:          url,
+		ThumbnailURL: url + "/thumbnail",
+		CreatedAt:    file.CreatedAt,
+	}
+}
+
+// mediaError is a problem with the media ids a chirp was sent with, as
+// opposed to failing to look them up
+type mediaError struct {
+	message string
+}
+
+func (e *mediaError) Error() string {
+	return e.message
+}
+
+// resolveMedia checks every id is an upload of userID that isn't attached to
+// a chirp yet. Ids that don't pass are reported as a *mediaError.
+func (cfg *apiConfig) resolveMedia(ctx context.Context, userID uuid.UUID, rawIDs []string) ([]uuid.UUID, error) {
+
+	var ids []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+
+	for _, rawID := range rawIDs {
+		id, err := uuid.Parse(rawID)
+
+		if err != nil {
+			return nil, &mediaError{fmt.Sprintf("invalid media id %q", rawID)}
+		}
+
+		if seen[id] {
+			return nil, &mediaError{fmt.Sprintf("media %v is listed twice", id)}
+		}
+		seen[id] = true
+
+		_, err = cfg.db.GetUnattachedMediaFile(ctx, database.GetUnattachedMediaFileParams{
+			ID:     id,
+			UserID: userID,
+		})
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &mediaError{fmt.Sprintf("media %v not found", id)}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// uploadMediaHandler takes an image as the "file" field of a multipart form
+func (cfg *apiConfig) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pending, err := cfg.db.CountUnattachedMedia(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to count unattached media: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if pending >= maxUnattachedMedia {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("You have %d uploads that aren't attached to a chirp, attach or wait for them to expire first", pending)}, http.StatusTooManyRequests)
+		if err != nil {
+			fmt.Printf("upload media: %v", err)
+		}
+		return
+	}
+
+	//leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.mediaMaxBytes+64<<10)
+
+	file, _, err := r.FormFile("file")
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		cfg.mediaTooLarge(w)
+		return
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: "Expected an image in the file field of a multipart form"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("upload media: %v", err)
+		}
+		return
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, cfg.mediaMaxBytes+1))
+
+	if err != nil {
+		log.Printf("Failed to read upload: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(data)) > cfg.mediaMaxBytes {
+		cfg.mediaTooLarge(w)
+		return
+	}
+
+	img, err := cfg.mediaProcessor.Process(data)
+
+	if err != nil {
+		status := http.StatusBadRequest
+		message := "Unable to read image"
+
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			status = http.StatusUnsupportedMediaType
+			message = "Only JPEG, PNG and GIF images can be uploaded"
+		case errors.Is(err, media.ErrTooManyPixels):
+			message = "Image dimensions too large"
+		}
+
+		log.Printf("Rejected upload: %v", err)
+		err = marshalHelper(w, errResponse{Error: message}, status)
+		if err != nil {
+			fmt.Printf("upload media: %v", err)
+		}
+		return
+	}
+
+	stored, err := cfg.storeMedia(r.Context(), userID, img)
+
+	if err != nil {
+		log.Printf("Failed to store upload: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, mediaToResponse(stored), http.StatusCreated)
+	if err != nil {
+		fmt.Printf("upload media: %v", err)
+	}
+}
+
+// storeMedia writes both blobs before the row, so a row always has its files
+func (cfg *apiConfig) storeMedia(ctx context.Context, userID uuid.UUID, img media.Image) (database.MediaFile, error) {
+
+	id := uuid.New()
+	blobKey := "media/" + id.String() + "/original"
+	thumbnailKey := "media/" + id.String() + "/thumbnail"
+
+	err := cfg.blobs.Put(ctx, blobKey, bytes.NewReader(img.Data))
+
+	if err == nil {
+		err = cfg.blobs.Put(ctx, thumbnailKey, bytes.NewReader(img.Thumbnail))
+	}
+
+	var stored database.MediaFile
+
+	if err == nil {
+		stored, err = cfg.db.CreateMediaFile(ctx, database.CreateMediaFileParams{
+			ID:            id,
+			UserID:        userID,
+			ContentType:   img.ContentType,
+			SizeBytes:     int64(len(img.Data)),
+			Width:         int32(img.Width),
+			Height:        int32(img.Height),
+			BlobKey:       blobKey,
+			ThumbnailKey:  thumbnailKey,
+			ThumbnailType: img.ThumbnailType,
+		})
+	}
+
+	if err != nil {
+		//nothing references the blobs
+		cfg.blobs.Delete(ctx, blobKey)
+		cfg.blobs.Delete(ctx, thumbnailKey)
+		return database.MediaFile{}, err
+	}
+
+	return stored, nil
+}
+
+func (cfg *apiConfig) mediaTooLarge(w http.ResponseWriter) {
+
+	err := marshalHelper(w, errResponse{Error: fmt.Sprintf("Uploads are limited to %d bytes", cfg.mediaMaxBytes)}, http.StatusRequestEntityTooLarge)
+	if err != nil {
+		fmt.Printf("upload media: %v", err)
+	}
+}
+
+func (cfg *apiConfig) getMediaHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, func(file database.MediaFile) (string, string) {
+		return file.BlobKey, file.ContentType
+	})
+}
+
+func (cfg *apiConfig) getMediaThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, func(file database.MediaFile) (string, string) {
+		return file.ThumbnailKey, file.ThumbnailType
+	})
+}
+
+// serveMedia streams one of a media file's blobs. Files never change once
+// uploaded, so they can be cached forever.
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request, pick func(database.MediaFile) (string, string)) {
+
+	mediaID, err := uuid.Parse(r.PathValue("mediaID"))
+
+	if err != nil {
+		log.Printf("Failed to parse media ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := cfg.db.GetMediaFile(r.Context(), mediaID)
+
+	if err != nil {
+		log.Printf("no media found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	key, contentType := pick(file)
+
+	content, err := cfg.blobs.Get(r.Context(), key)
+
+	if errors.Is(err, blob.ErrNotFound) {
+		log.Printf("Blob %v of media %v is missing", key, file.ID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to open blob %v: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Failed to send media %v: %v", file.ID, err)
+	}
+}
+
+// reapUnattachedMedia purges uploads that were never attached to a chirp
+// within mediaUnattachedTTL, every interval until ctx is done
+func (cfg *apiConfig) reapUnattachedMedia(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			purged, err := cfg.purgeAbandonedMedia(ctx, time.Now().UTC().Add(-cfg.mediaUnattachedTTL))
+
+			if err != nil {
+				log.Printf("Failed to purge unattached media: %v", err)
+			}
+
+			if err != nil || purged < mediaReaperBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeAbandonedMedia deletes a batch of uploads made before uploadedBefore
+// that were never attached to a chirp, nor kept by a draft, and returns how
+// many.
+func (cfg *apiConfig) purgeAbandonedMedia(ctx context.Context, uploadedBefore time.Time) (int, error) {
+
+	var files []database.MediaFile
+
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+
+		files, err = q.GetAbandonedMediaFiles(ctx, database.GetAbandonedMediaFilesParams{
+			UploadedBefore: uploadedBefore,
+			BatchSize:      mediaReaperBatchSize,
+		})
+
+		if err != nil || len(files) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(files))
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+
+		return q.DeleteMediaFiles(ctx, ids)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	cfg.deleteMediaBlobs(ctx, files)
+
+	if len(files) > 0 {
+		log.Printf("Purged %d unattached uploads", len(files))
+	}
+
+	return len(files), nil
+}
+
+// deleteMediaBlobs removes the blobs of deleted media, a failure only
+// leaves an unreferenced file behind
+func (cfg *apiConfig) deleteMediaBlobs(ctx context.Context, files []database.MediaFile) {
+
+	for _, file := range files {
+		for _, key := range []string{file.BlobKey, file.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+
+			if err := cfg.blobs.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete blob %v of media %v: %v", key, file.ID, err)
+			}
+		}
+	}
+}
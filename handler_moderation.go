@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/moderation"
+	"github.com/google/uuid"
+)
+
+type chirpFlagResponse struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Filter    string    `json:"filter"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// logRejection keeps why moderation rejected a chirp in the log. Posters
+// only get a generic message, the reasons would spell out the rules.
+func logRejection(userID uuid.UUID, verdict moderation.Verdict) {
+
+	for _, finding := range verdict.Findings {
+		if finding.Action == moderation.ActionReject {
+			log.Printf("Moderation rejected a chirp by %v: %v: %v", userID, finding.Filter, finding.Reason)
+		}
+	}
+}
+
+// flagChirp records the findings that flagged a chirp for review
+func (cfg *apiConfig) flagChirp(ctx context.Context, chirp database.Chirp, verdict moderation.Verdict) {
+
+	for _, finding := range verdict.Findings {
+		if finding.Action != moderation.ActionFlag {
+			continue
+		}
+
+		err := cfg.db.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+			ChirpID: chirp.ID,
+			Filter:  finding.Filter,
+			Reason:  finding.Reason,
+		})
+
+		if err != nil {
+			log.Printf("Failed to flag chirp %v: %v", chirp.ID, err)
+		}
+	}
+}
+
+func (cfg *apiConfig) chirpFlagsHandler(w http.ResponseWriter, r *http.Request) {
+
+	flags, err := cfg.db.GetUnreviewedChirpFlags(r.Context(), maxPageSize)
+
+	if err != nil {
+		log.Printf("Failed to retreive chirp flags: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := []chirpFlagResponse{}
+
+	for _, flag := range flags {
+		res = append(res, chirpFlagResponse{
+			ID:        flag.ID,
+			ChirpID:   flag.ChirpID,
+			Filter:    flag.Filter,
+			Reason:    flag.Reason,
+			CreatedAt: flag.CreatedAt,
+		})
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("chirp flags: %v", err)
+	}
+}
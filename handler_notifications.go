@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/notify"
+	"github.com/google/uuid"
+)
+
+// how many of a grouped notification's actors are listed
+const notificationActorPreview = 3
+
+type notificationResponse struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       notify.Type `json:"type"`
+	ChirpID    *uuid.UUID  `json:"chirp_id,omitempty"`
+	ActorIDs   []uuid.UUID `json:"actor_ids"`
+	ActorCount int32       `json:"actor_count"`
+	Summary    string      `json:"summary"`
+	Read       bool        `json:"read"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+type notificationListResponse struct {
+	Notifications []notificationResponse `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
+
+type markNotificationsReadRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+	All bool        `json:"all"`
+}
+
+type markNotificationsReadResponse struct {
+	Marked      int64 `json:"marked"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type notificationPreferencesResponse struct {
+	Replies  bool `json:"replies"`
+	Likes    bool `json:"likes"`
+	Mentions bool `json:"mentions"`
+	Follows  bool `json:"follows"`
+}
+
+// omitted fields keep their current value
+type notificationPreferencesRequest struct {
+	Replies  *bool `json:"replies"`
+	Likes    *bool `json:"likes"`
+	Mentions *bool `json:"mentions"`
+	Follows  *bool `json:"follows"`
+}
+
+// notify records that actorID did something to recipientID, merging it into
+// the recipient's unread group if there is one, and pushes the group to
+// websocket clients. Failing to notify never fails the action behind it.
+func (cfg *apiConfig) notify(ctx context.Context, recipientID, actorID uuid.UUID, t notify.Type, chirpID uuid.NullUUID) {
+
+	if recipientID == actorID {
+		return
+	}
+
+	//nothing reaches a user from someone they muted or are blocked with
+	hidden, err := cfg.hiddenUsersFor(ctx, recipientID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users of %v: %v", recipientID, err)
+		return
+	}
+
+	if hidden[actorID] {
+		return
+	}
+
+	prefs, err := cfg.notificationPreferences(ctx, recipientID)
+
+	if err != nil {
+		log.Printf("Failed to load notification preferences of %v: %v", recipientID, err)
+		return
+	}
+
+	if !prefs.Allows(t) {
+		return
+	}
+
+	var notification database.Notification
+	var added int64
+
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		notification, err = q.UpsertNotification(ctx, database.UpsertNotificationParams{
+			UserID:        recipientID,
+			Type:          string(t),
+			GroupKey:      notify.GroupKey(t, chirpID),
+			ChirpID:       chirpID,
+			LatestActorID: actorID,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		added, err = q.AddNotificationActor(ctx, database.AddNotificationActorParams{
+			NotificationID: notification.ID,
+			ActorID:        actorID,
+		})
+
+		//an actor already in the notification leaves it where it is
+		if err != nil || added == 0 {
+			return err
+		}
+
+		notification, err = q.RefreshNotificationActorCount(ctx, database.RefreshNotificationActorCountParams{
+			ID:            notification.ID,
+			LatestActorID: actorID,
+		})
+		return err
+	})
+
+	if err != nil {
+		log.Printf("Failed to notify %v of %v: %v", recipientID, t, err)
+		return
+	}
+
+	//liking, unliking and liking again doesn't notify twice
+	if added == 0 {
+		return
+	}
+
+	cfg.publish(realtimeNotification, recipientID, cfg.notificationsToResponse(ctx, []database.Notification{notification})[0])
+}
+
+// notificationPreferences returns the defaults for users that never saved any
+func (cfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (notify.Preferences, error) {
+
+	row, err := cfg.db.GetNotificationPreferences(ctx, userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return notify.Defaults(), nil
+	}
+
+	if err != nil {
+		return notify.Preferences{}, err
+	}
+
+	return notify.Preferences{
+		Replies:  row.Replies,
+		Likes:    row.Likes,
+		Mentions: row.Mentions,
+		Follows:  row.Follows,
+	}, nil
+}
+
+// notificationsToResponse builds the responses of a page of notifications,
+// loading the actors of all of them with two queries
+func (cfg *apiConfig) notificationsToResponse(ctx context.Context, notifications []database.Notification) []notificationResponse {
+
+	ids := make([]uuid.UUID, 0, len(notifications))
+	latestIDs := make([]uuid.UUID, 0, len(notifications))
+	for _, notification := range notifications {
+		ids = append(ids, notification.ID)
+		latestIDs = append(latestIDs, notification.LatestActorID)
+	}
+
+	actorIDs := map[uuid.UUID][]uuid.UUID{}
+
+	rows, err := cfg.db.GetNotificationsActorIDs(ctx, database.GetNotificationsActorIDsParams{
+		NotificationIds: ids,
+		PerNotification: notificationActorPreview,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive notification actors: %v", err)
+	}
+
+	for _, row := range rows {
+		actorIDs[row.NotificationID] = append(actorIDs[row.NotificationID], row.ActorID)
+	}
+
+	latestActors := map[uuid.UUID]database.User{}
+
+	users, err := cfg.db.GetUsersByIDs(ctx, latestIDs)
+
+	if err != nil {
+		log.Printf("Failed to retreive notification actors: %v", err)
+	}
+
+	for _, user := range users {
+		latestActors[user.ID] = user
+	}
+
+	res := make([]notificationResponse, 0, len(notifications))
+
+	for _, notification := range notifications {
+		item := notificationResponse{
+			ID:         notification.ID,
+			Type:       notify.Type(notification.Type),
+			ActorIDs:   []uuid.UUID{},
+			ActorCount: notification.ActorCount,
+			Read:       notification.ReadAt.Valid,
+			CreatedAt:  notification.CreatedAt,
+			UpdatedAt:  notification.UpdatedAt,
+		}
+
+		if notification.ChirpID.Valid {
+			item.ChirpID = &notification.ChirpID.UUID
+		}
+
+		if len(actorIDs[notification.ID]) > 0 {
+			item.ActorIDs = actorIDs[notification.ID]
+		}
+
+		//the summary names the latest actor, falling back to "N people"
+		actor := ""
+
+		if user, ok := latestActors[notification.LatestActorID]; ok {
+			actor = user.DisplayName
+			if user.Handle.Valid {
+				actor = "@" + user.Handle.String
+			}
+		}
+
+		item.Summary = notify.Summary(item.Type, actor, int(notification.ActorCount))
+
+		res = append(res, item)
+	}
+
+	return res
+}
+
+func (cfg *apiConfig) notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("list notifications: %v", err)
+		}
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//the cursor's time is the notification's updated_at, groups move to the
+	//top when someone joins them
+	notifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      r.URL.Query().Get("unread") == "true",
+		BeforeUpdatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive notifications: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to count unread notifications: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//groups last joined by someone since muted or blocked are left out
+	//after the cursor is taken, so a page may come back short
+	visible := make([]database.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		if !hidden[notification.LatestActorID] {
+			visible = append(visible, notification)
+		}
+	}
+
+	res := notificationListResponse{
+		Notifications: cfg.notificationsToResponse(r.Context(), visible),
+		UnreadCount:   unread,
+	}
+
+	if len(notifications) == int(limit) {
+		last := notifications[len(notifications)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.UpdatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list notifications: %v", err)
+	}
+}
+
+// markNotificationsReadHandler marks the listed notifications, or all of them
+// with {"all": true}, as read
+func (cfg *apiConfig) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+
+	var request markNotificationsReadRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil || (!request.All && len(request.IDs) == 0) {
+		err = marshalHelper(w, errResponse{Error: "Expected ids or all"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("mark notifications read: %v", err)
+		}
+		return
+	}
+
+	cfg.markNotificationsRead(w, r, request)
+}
+
+func (cfg *apiConfig) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+
+	if err != nil {
+		log.Printf("Failed to parse notification ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cfg.markNotificationsRead(w, r, markNotificationsReadRequest{IDs: []uuid.UUID{notificationID}})
+}
+
+func (cfg *apiConfig) markNotificationsRead(w http.ResponseWriter, r *http.Request, request markNotificationsReadRequest) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	var marked int64
+
+	if request.All {
+		marked, err = cfg.db.MarkAllNotificationsRead(r.Context(), userID)
+	} else {
+		marked, err = cfg.db.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: userID,
+			Ids:    request.IDs,
+		})
+	}
+
+	if err != nil {
+		log.Printf("Failed to mark notifications read: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to count unread notifications: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, markNotificationsReadResponse{Marked: marked, UnreadCount: unread}, http.StatusOK)
+	if err != nil {
+		fmt.Printf("mark notifications read: %v", err)
+	}
+}
+
+func (cfg *apiConfig) notificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	prefs, err := cfg.notificationPreferences(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to load notification preferences: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, notificationPreferencesResponse(prefs), http.StatusOK)
+	if err != nil {
+		fmt.Printf("notification preferences: %v", err)
+	}
+}
+
+func (cfg *apiConfig) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	var request notificationPreferencesRequest
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Invalid preferences: %v", err)}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("update notification preferences: %v", err)
+		}
+		return
+	}
+
+	prefs, err := cfg.notificationPreferences(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to load notification preferences: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for _, field := range []struct {
+		value *bool
+		pref  *bool
+	}{
+		{request.Replies, &prefs.Replies},
+		{request.Likes, &prefs.Likes},
+		{request.Mentions, &prefs.Mentions},
+		{request.Follows, &prefs.Follows},
+	} {
+		if field.value != nil {
+			*field.pref = *field.value
+		}
+	}
+
+	_, err = cfg.db.UpsertNotificationPreferences(r.Context(), database.UpsertNotificationPreferencesParams{
+		UserID:   userID,
+		Replies:  prefs.Replies,
+		Likes:    prefs.Likes,
+		Mentions: prefs.Mentions,
+		Follows:  prefs.Follows,
+	})
+
+	if err != nil {
+		log.Printf("Failed to save notification preferences: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, notificationPreferencesResponse(prefs), http.StatusOK)
+	if err != nil {
+		fmt.Printf("update notification preferences: %v", err)
+	}
+}
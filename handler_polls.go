@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/moderation"
+	"github.com/JonMunkholm/server/internal/poll"
+	"github.com/google/uuid"
+)
+
+// codes of the errors voting can run into
+const (
+	pollErrNotFound      = "poll_not_found"
+	pollErrOwnPoll       = "own_poll"
+	pollErrClosed        = "poll_closed"
+	pollErrAlreadyVoted  = "already_voted"
+	pollErrInvalidOption = "invalid_option"
+)
+
+type pollParams struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type pollResponse struct {
+	Options    []pollOptionResponse `json:"options"`
+	TotalVotes int64                `json:"total_votes"`
+	ClosesAt   time.Time            `json:"closes_at"`
+	Closed     bool                 `json:"closed"`
+	//positions of the leading options once the poll is closed
+	Winners []int `json:"winners,omitempty"`
+	//the position the requesting user voted for, when known
+	VotedOption *int32 `json:"voted_option,omitempty"`
+}
+
+type pollOptionResponse struct {
+	Position int32  `json:"position"`
+	Text     string `json:"text"`
+	Votes    int64  `json:"votes"`
+	Percent  int    `json:"percent"`
+}
+
+type voteParams struct {
+	Option *int32 `json:"option"`
+}
+
+// preparePoll validates a poll for a chirp being prepared and returns its
+// options. Options are moderated like the body: masked words are replaced
+// and a rejected option rejects the chirp.
+func (cfg *apiConfig) preparePoll(prepared preparedChirp, request pollParams) ([]string, error) {
+
+	if prepared.params.Kind == chirpKindRechirp {
+		return nil, errors.New("A rechirp can't have a poll")
+	}
+
+	if len(prepared.mediaIDs) > 0 {
+		return nil, errors.New("A chirp can't have both media and a poll")
+	}
+
+	options, err := poll.Validate(request.Options, request.ClosesAt, time.Now())
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid poll: %w", err)
+	}
+
+	for i, option := range options {
+		verdict := cfg.moderation.Check(option)
+
+		if verdict.Action == moderation.ActionReject {
+			logRejection(prepared.params.UserID, verdict)
+			return nil, errors.New("Poll option rejected by moderation")
+		}
+
+		options[i] = verdict.Body
+	}
+
+	return options, nil
+}
+
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, options []string, closesAt time.Time) error {
+
+	err := q.CreatePoll(ctx, database.CreatePollParams{ChirpID: chirpID, ClosesAt: closesAt})
+
+	if err != nil {
+		return err
+	}
+
+	for i, option := range options {
+		err = q.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Text:     option,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadPoll returns the current tallies of a chirp's poll, sql.ErrNoRows when
+// it has none
+func (cfg *apiConfig) loadPoll(ctx context.Context, chirpID uuid.UUID) (pollResponse, error) {
+
+	stored, err := cfg.db.GetPoll(ctx, chirpID)
+
+	if err != nil {
+		return pollResponse{}, err
+	}
+
+	rows, err := cfg.db.GetPollResults(ctx, chirpID)
+
+	if err != nil {
+		return pollResponse{}, err
+	}
+
+	return pollToResponse(stored, rows), nil
+}
+
+// pollToResponse tallies a poll from its options in position order
+func pollToResponse(stored database.Poll, rows []database.GetPollResultsRow) pollResponse {
+
+	results := poll.Results{ClosesAt: stored.ClosesAt}
+	for _, row := range rows {
+		results.Votes = append(results.Votes, row.Votes)
+	}
+
+	percentages := results.Percentages()
+
+	res := pollResponse{
+		Options:    []pollOptionResponse{},
+		TotalVotes: results.Total(),
+		ClosesAt:   stored.ClosesAt,
+		Closed:     results.Closed(time.Now().UTC()),
+	}
+
+	for i, row := range rows {
+		res.Options = append(res.Options, pollOptionResponse{
+			Position: row.Position,
+			Text:     row.Text,
+			Votes:    row.Votes,
+			Percent:  percentages[i],
+		})
+	}
+
+	if res.Closed {
+		res.Winners = results.Winners()
+	}
+
+	return res
+}
+
+func pollError(w http.ResponseWriter, status int, code, message string) {
+
+	err := marshalHelper(w, errResponse{Error: message, Code: code}, status)
+	if err != nil {
+		fmt.Printf("vote: %v", err)
+	}
+}
+
+// pollChirp returns the chirp a poll is on, answering 404 itself when it
+// doesn't exist or userID is blocked with its author. The poll is only there
+// as long as its chirp is.
+func (cfg *apiConfig) pollChirp(w http.ResponseWriter, r *http.Request, chirpID, userID uuid.UUID) (database.Chirp, bool) {
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+
+	if err != nil {
+		log.Printf("no chirp found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return database.Chirp{}, false
+	}
+
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		UserID:  userID,
+		OtherID: chirp.UserID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to check blocks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return database.Chirp{}, false
+	}
+
+	if blocked {
+		w.WriteHeader(http.StatusNotFound)
+		return database.Chirp{}, false
+	}
+
+	return chirp, true
+}
+
+// getPollHandler returns a chirp's poll along with the option the user voted
+// for, if any
+func (cfg *apiConfig) getPollHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	chirp, ok := cfg.pollChirp(w, r, chirpID, userID)
+
+	if !ok {
+		return
+	}
+
+	res, err := cfg.loadPoll(r.Context(), chirp.ID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		pollError(w, http.StatusNotFound, pollErrNotFound, "Chirp has no poll")
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to retreive poll: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	position, err := cfg.db.GetPollVote(r.Context(), database.GetPollVoteParams{ChirpID: chirp.ID, UserID: userID})
+
+	if err == nil {
+		res.VotedOption = &position
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to retreive poll vote: %v", err)
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("get poll: %v", err)
+	}
+}
+
+// votePollHandler casts the user's vote. Votes are final, the database
+// allows a single one per user and poll.
+func (cfg *apiConfig) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		log.Printf("Failed to parse chirp ID")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	var request voteParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil || request.Option == nil {
+		pollError(w, http.StatusBadRequest, pollErrInvalidOption, "Expected the position of an option")
+		return
+	}
+
+	chirp, ok := cfg.pollChirp(w, r, chirpID, userID)
+
+	if !ok {
+		return
+	}
+
+	current, err := cfg.loadPoll(r.Context(), chirp.ID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		pollError(w, http.StatusNotFound, pollErrNotFound, "Chirp has no poll")
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to retreive poll: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if chirp.UserID == userID {
+		pollError(w, http.StatusForbidden, pollErrOwnPoll, "You can't vote on your own poll")
+		return
+	}
+
+	if current.Closed {
+		pollError(w, http.StatusConflict, pollErrClosed, "Poll is closed")
+		return
+	}
+
+	if *request.Option < 0 || int(*request.Option) >= len(current.Options) {
+		pollError(w, http.StatusBadRequest, pollErrInvalidOption, fmt.Sprintf("Poll options are 0 to %d", len(current.Options)-1))
+		return
+	}
+
+	//the insert only happens while the poll is open, so a vote can't sneak
+	//in after it closed
+	voted, err := cfg.db.VotePoll(r.Context(), database.VotePollParams{
+		UserID:   userID,
+		Position: *request.Option,
+		ChirpID:  chirp.ID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to vote: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if voted == 0 && !time.Now().UTC().Before(current.ClosesAt) {
+		pollError(w, http.StatusConflict, pollErrClosed, "Poll is closed")
+		return
+	}
+
+	if voted == 0 {
+		pollError(w, http.StatusConflict, pollErrAlreadyVoted, "You already voted on this poll")
+		return
+	}
+
+	res, err := cfg.loadPoll(r.Context(), chirp.ID)
+
+	if err != nil {
+		log.Printf("Failed to retreive poll: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.VotedOption = request.Option
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("vote: %v", err)
+	}
+}
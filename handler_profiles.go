@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const maxDisplayNameLength = 50
+const maxBioLength = 160
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+type updateProfileParams struct {
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+}
+
+// public view of a user, never includes the email
+type profileResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	Handle           string     `json:"handle"`
+	DisplayName      string     `json:"display_name"`
+	Bio              string     `json:"bio"`
+	IsChirpRed       bool       `json:"is_chirpy_red"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ProfileUpdatedAt *time.Time `json:"profile_updated_at"`
+}
+
+func profileToResponse(user database.User) profileResponse {
+
+	res := profileResponse{
+		ID:          user.ID,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		IsChirpRed:  user.IsChirpRed,
+		CreatedAt:   user.CreatedAt,
+	}
+
+	if user.ProfileUpdatedAt.Valid {
+		res.ProfileUpdatedAt = &user.ProfileUpdatedAt.Time
+	}
+
+	return res
+}
+
+// handles are optional, an empty handle is stored as NULL
+func parseHandle(handle string) (sql.NullString, error) {
+
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+
+	if handle == "" {
+		return sql.NullString{}, nil
+	}
+
+	if !handlePattern.MatchString(handle) {
+		return sql.NullString{}, fmt.Errorf("handle must be 3-15 letters, numbers or underscores")
+	}
+
+	return sql.NullString{String: handle, Valid: true}, nil
+}
+
+// resolveUserRef looks a user up by UUID or by @handle
+func (cfg *apiConfig) resolveUserRef(r *http.Request, ref string) (database.User, error) {
+
+	if userID, err := uuid.Parse(ref); err == nil {
+		return cfg.db.GetUserByID(r.Context(), userID)
+	}
+
+	return cfg.db.GetUserByHandle(r.Context(), strings.TrimPrefix(ref, "@"))
+}
+
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, err := cfg.db.GetUserByHandle(r.Context(), strings.TrimPrefix(r.PathValue("handle"), "@"))
+
+	if err != nil {
+		log.Printf("Failed to retreive profile: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//users blocked either way can't see each other's profile
+	if viewerID := cfg.optionalViewer(r); viewerID != uuid.Nil {
+		blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+			UserID:  viewerID,
+			OtherID: user.ID,
+		})
+
+		if err != nil {
+			log.Printf("Failed to check blocks: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if blocked {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	err = marshalHelper(w, profileToResponse(user), http.StatusOK)
+	if err != nil {
+		fmt.Printf("get profile: %v", err)
+	}
+}
+
+func (cfg *apiConfig) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	decoder := json.NewDecoder(r.Body)
+
+	var request updateProfileParams
+
+	err := decoder.Decode(&request)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Error decoding parameters, unable to update profile: %v", err)}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("update profile: %v", err)
+		}
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	handle, err := parseHandle(request.Handle)
+
+	if err == nil && utf8.RuneCountInString(request.DisplayName) > maxDisplayNameLength {
+		err = fmt.Errorf("display name is longer than %d characters", maxDisplayNameLength)
+	}
+
+	if err == nil && utf8.RuneCountInString(request.Bio) > maxBioLength {
+		err = fmt.Errorf("bio is longer than %d characters", maxBioLength)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("update profile: %v", err)
+		}
+		return
+	}
+
+	user, err := cfg.db.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+		ID:          userID,
+		Handle:      handle,
+		DisplayName: strings.TrimSpace(request.DisplayName),
+		Bio:         strings.TrimSpace(request.Bio),
+	})
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		err = marshalHelper(w, errResponse{Error: "Handle is already taken"}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("update profile: %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to update profile: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, profileToResponse(user), http.StatusOK)
+	if err != nil {
+		fmt.Printf("update profile: %v", err)
+	}
+}
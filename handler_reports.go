@@ -0,0 +1,824 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/report"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// moderation actions recorded in the audit trail
+const (
+	modActionClaimReport   = "report.claimed"
+	modActionResolveReport = "report.resolved"
+	modActionDismissReport = "report.dismissed"
+	modActionDeleteChirp   = "chirp.deleted"
+	modActionSuspendUser   = "user.suspended"
+	modActionWarnUser      = "user.warned"
+)
+
+// codes of the errors moderators can run into
+const (
+	reportErrClosed     = "report_closed"
+	reportErrClaimed    = "report_claimed"
+	reportErrNotClaimed = "report_not_claimed"
+)
+
+const maxModerationNoteLength = 500
+
+type reportParams struct {
+	ChirpID  *uuid.UUID `json:"chirp_id"`
+	UserID   *uuid.UUID `json:"user_id"`
+	Category string     `json:"category"`
+	Details  string     `json:"details"`
+}
+
+type resolveReportParams struct {
+	Resolution string `json:"resolution"`
+	//shown to the user for warnings and suspensions
+	Note string `json:"note"`
+	//suspensions without an end last until lifted
+	SuspendUntil *time.Time `json:"suspend_until"`
+}
+
+type dismissReportParams struct {
+	Note string `json:"note"`
+}
+
+type reportResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	ReporterID *uuid.UUID `json:"reporter_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	Category   string     `json:"category"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	ClaimedBy  *uuid.UUID `json:"claimed_by"`
+	ClaimedAt  *time.Time `json:"claimed_at"`
+	Resolution string     `json:"resolution,omitempty"`
+	Note       string     `json:"note"`
+	ClosedAt   *time.Time `json:"closed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	//the audit trail, only when a single report is fetched
+	Actions []moderationActionResponse `json:"actions,omitempty"`
+}
+
+type reportPageResponse struct {
+	Reports    []reportResponse `json:"reports"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type moderationActionResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	Action    string     `json:"action"`
+	ReportID  *uuid.UUID `json:"report_id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type moderationActionPageResponse struct {
+	Actions    []moderationActionResponse `json:"actions"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+}
+
+type warningResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type warningPageResponse struct {
+	Warnings   []warningResponse `json:"warnings"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func reportToResponse(stored database.Report) reportResponse {
+
+	return reportResponse{
+		ID:         stored.ID,
+		ReporterID: nullUUIDPtr(stored.ReporterID),
+		UserID:     stored.UserID,
+		ChirpID:    nullUUIDPtr(stored.ChirpID),
+		Category:   stored.Category,
+		Details:    stored.Details,
+		Status:     stored.Status,
+		ClaimedBy:  nullUUIDPtr(stored.ClaimedBy),
+		ClaimedAt:  nullTimePtr(stored.ClaimedAt),
+		Resolution: stored.Resolution.String,
+		Note:       stored.Note,
+		ClosedAt:   nullTimePtr(stored.ClosedAt),
+		CreatedAt:  stored.CreatedAt,
+		UpdatedAt:  stored.UpdatedAt,
+	}
+}
+
+func moderationActionToResponse(action database.ModerationAction) moderationActionResponse {
+
+	return moderationActionResponse{
+		ID:        action.ID,
+		ActorID:   action.ActorID,
+		Action:    action.Action,
+		ReportID:  nullUUIDPtr(action.ReportID),
+		UserID:    nullUUIDPtr(action.UserID),
+		ChirpID:   nullUUIDPtr(action.ChirpID),
+		Note:      action.Note,
+		CreatedAt: action.CreatedAt,
+	}
+}
+
+// recordModeration adds an action on a report to the audit trail
+func recordModeration(ctx context.Context, q *database.Queries, moderatorID uuid.UUID, action string, stored database.Report, chirpID uuid.NullUUID, note string) error {
+
+	return q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ActorID:  moderatorID,
+		Action:   action,
+		ReportID: uuid.NullUUID{UUID: stored.ID, Valid: true},
+		UserID:   uuid.NullUUID{UUID: stored.UserID, Valid: true},
+		ChirpID:  chirpID,
+		Note:     note,
+	})
+}
+
+func reportError(w http.ResponseWriter, status int, code, message string) {
+
+	err := marshalHelper(w, errResponse{Error: message, Code: code}, status)
+	if err != nil {
+		fmt.Printf("report: %v", err)
+	}
+}
+
+// writeReportStateError answers errors from the report state checks, it
+// returns false for any other error
+func writeReportStateError(w http.ResponseWriter, err error) bool {
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, report.ErrClosed):
+		reportError(w, http.StatusConflict, reportErrClosed, "Report is already closed")
+	case errors.Is(err, report.ErrClaimed):
+		reportError(w, http.StatusConflict, reportErrClaimed, "Report is claimed by another moderator")
+	case errors.Is(err, report.ErrNotClaimed):
+		reportError(w, http.StatusConflict, reportErrNotClaimed, "Claim the report before closing it")
+	default:
+		return false
+	}
+
+	return true
+}
+
+// createReportHandler reports a chirp, and with it its author, or an
+// account. Reporters can't tell what happens to their reports.
+func (cfg *apiConfig) createReportHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	var request reportParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err == nil && (request.ChirpID == nil) == (request.UserID == nil) {
+		err = errors.New("Report either a chirp_id or a user_id")
+	}
+
+	var category report.Category
+	var details string
+
+	if err == nil {
+		category, details, err = report.Validate(request.Category, request.Details)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("create report: %v", err)
+		}
+		return
+	}
+
+	params := database.CreateReportParams{
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		Category:   string(category),
+		Details:    details,
+	}
+
+	if request.ChirpID != nil {
+		chirp, err := cfg.db.GetChirp(r.Context(), *request.ChirpID)
+
+		if err != nil {
+			log.Printf("no chirp found: %v", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		params.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+		params.UserID = chirp.UserID
+	} else {
+		params.UserID = *request.UserID
+	}
+
+	if params.UserID == userID {
+		err = marshalHelper(w, errResponse{Error: "You can't report yourself"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("create report: %v", err)
+		}
+		return
+	}
+
+	stored, err := cfg.db.CreateReport(r.Context(), params)
+
+	var pqErr *pq.Error
+
+	//foreign_key_violation, the reported user doesn't exist
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//unique_violation on reports_pending_idx
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		err = marshalHelper(w, errResponse{Error: "You already reported this and it is being looked at"}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("create report: %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to create report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, reportToResponse(stored), http.StatusCreated)
+	if err != nil {
+		fmt.Printf("create report: %v", err)
+	}
+}
+
+// warningsHandler lists the warnings moderators gave the user, newest first
+func (cfg *apiConfig) warningsHandler(w http.ResponseWriter, r *http.Request) {
+	//expecting session/JWT token as bearer token
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Unable to retrieve Bearer token: %v", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to validate user: %v", err)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("warnings: %v", err)
+		}
+		return
+	}
+
+	warnings, err := cfg.db.GetWarnings(r.Context(), database.GetWarningsParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive warnings: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := warningPageResponse{
+		Warnings: []warningResponse{},
+	}
+
+	for _, warning := range warnings {
+		res.Warnings = append(res.Warnings, warningResponse{
+			ID:        warning.ID,
+			Reason:    warning.Reason,
+			CreatedAt: warning.CreatedAt,
+		})
+	}
+
+	if len(warnings) == int(limit) {
+		last := warnings[len(warnings)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("warnings: %v", err)
+	}
+}
+
+// reportsHandler is the moderation queue, newest first, optionally only
+// reports in one ?status=
+func (cfg *apiConfig) reportsHandler(w http.ResponseWriter, r *http.Request) {
+
+	pos, limit, err := parsePage(r)
+
+	var status sql.NullString
+
+	if raw := r.URL.Query().Get("status"); err == nil && raw != "" {
+		_, err = report.ParseStatus(raw)
+		status = sql.NullString{String: raw, Valid: true}
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("reports: %v", err)
+		}
+		return
+	}
+
+	reports, err := cfg.db.ListReports(r.Context(), database.ListReportsParams{
+		Status:          status,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive reports: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := reportPageResponse{
+		Reports: []reportResponse{},
+	}
+
+	for _, stored := range reports {
+		res.Reports = append(res.Reports, reportToResponse(stored))
+	}
+
+	if len(reports) == int(limit) {
+		last := reports[len(reports)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("reports: %v", err)
+	}
+}
+
+// getReportHandler returns a report with its audit trail
+func (cfg *apiConfig) getReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	stored, err := cfg.db.GetReport(r.Context(), reportID)
+
+	if err != nil {
+		log.Printf("no report found: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	actions, err := cfg.db.GetReportActions(r.Context(), uuid.NullUUID{UUID: stored.ID, Valid: true})
+
+	if err != nil {
+		log.Printf("Failed to retreive report actions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := reportToResponse(stored)
+
+	for _, action := range actions {
+		res.Actions = append(res.Actions, moderationActionToResponse(action))
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("get report: %v", err)
+	}
+}
+
+// claimReportHandler assigns a report to the moderator, so nobody else acts
+// on it. Claiming your own claim again is a no-op.
+func (cfg *apiConfig) claimReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	moderatorID := staffUser(r.Context()).ID
+
+	var claimed database.Report
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		stored, err := q.GetReportForUpdate(r.Context(), reportID)
+
+		if err != nil {
+			return err
+		}
+
+		err = report.CheckClaim(report.Status(stored.Status), stored.ClaimedBy, moderatorID)
+
+		if err != nil {
+			return err
+		}
+
+		claimed = stored
+
+		if stored.Status == string(report.StatusClaimed) {
+			return nil
+		}
+
+		claimed, err = q.ClaimReport(r.Context(), database.ClaimReportParams{
+			ID:        stored.ID,
+			ClaimedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return recordModeration(r.Context(), q, moderatorID, modActionClaimReport, claimed, claimed.ChirpID, "")
+	})
+
+	if writeReportStateError(w, err) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to claim report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, reportToResponse(claimed), http.StatusOK)
+	if err != nil {
+		fmt.Printf("claim report: %v", err)
+	}
+}
+
+// resolveReportHandler upholds a claimed report: the reported chirp is
+// deleted, or its author or the reported account is suspended or warned
+func (cfg *apiConfig) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	actor := staffUser(r.Context())
+	moderatorID := actor.ID
+
+	var request resolveReportParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	var resolution report.Resolution
+
+	if err == nil {
+		resolution, err = report.ParseResolution(request.Resolution)
+	}
+
+	request.Note = strings.TrimSpace(request.Note)
+
+	if err == nil && utf8.RuneCountInString(request.Note) > maxModerationNoteLength {
+		err = fmt.Errorf("note is longer than %d characters", maxModerationNoteLength)
+	}
+
+	if err == nil && resolution != report.ResolutionDeleteChirp && request.Note == "" {
+		err = errors.New("A note is required, the user is shown it")
+	}
+
+	if err == nil && request.SuspendUntil != nil && resolution != report.ResolutionSuspendUser {
+		err = errors.New("suspend_until only applies to suspensions")
+	}
+
+	if err == nil && request.SuspendUntil != nil && !request.SuspendUntil.After(time.Now()) {
+		err = errors.New("suspend_until must be in the future")
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("resolve report: %v", err)
+		}
+		return
+	}
+
+	var resolved database.Report
+	var deleted int64
+	var suspended bool
+	errNoChirp := errors.New("report is not about a chirp")
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		stored, err := q.GetReportForUpdate(r.Context(), reportID)
+
+		if err != nil {
+			return err
+		}
+
+		err = report.CheckClose(report.Status(stored.Status), stored.ClaimedBy, moderatorID)
+
+		if err != nil {
+			return err
+		}
+
+		reportRef := uuid.NullUUID{UUID: stored.ID, Valid: true}
+		moderatorRef := uuid.NullUUID{UUID: moderatorID, Valid: true}
+		action := modActionWarnUser
+
+		switch resolution {
+		case report.ResolutionDeleteChirp:
+			if !stored.ChirpID.Valid {
+				return errNoChirp
+			}
+
+			action = modActionDeleteChirp
+
+			//already deleted by its author is as good
+			deleted, err = q.DeleteChirp(r.Context(), database.DeleteChirpParams{
+				ID:     stored.ChirpID.UUID,
+				UserID: stored.UserID,
+			})
+
+		case report.ResolutionSuspendUser:
+			err = checkSanction(r.Context(), q, actor, stored.UserID)
+
+			if err != nil {
+				return err
+			}
+
+			action = modActionSuspendUser
+
+			var until sql.NullTime
+			if request.SuspendUntil != nil {
+				until = sql.NullTime{Time: request.SuspendUntil.UTC(), Valid: true}
+			}
+
+			_, err = q.CreateSuspension(r.Context(), database.CreateSuspensionParams{
+				UserID:    stored.UserID,
+				Reason:    request.Note,
+				ReportID:  reportRef,
+				CreatedBy: moderatorRef,
+				ExpiresAt: until,
+			})
+			suspended = err == nil
+
+		case report.ResolutionWarnUser:
+			err = checkSanction(r.Context(), q, actor, stored.UserID)
+
+			if err != nil {
+				return err
+			}
+
+			_, err = q.CreateWarning(r.Context(), database.CreateWarningParams{
+				UserID:    stored.UserID,
+				Reason:    request.Note,
+				ReportID:  reportRef,
+				CreatedBy: moderatorRef,
+			})
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = recordModeration(r.Context(), q, moderatorID, action, stored, stored.ChirpID, request.Note)
+
+		if err != nil {
+			return err
+		}
+
+		resolved, err = q.CloseReport(r.Context(), database.CloseReportParams{
+			ID:         stored.ID,
+			Status:     string(report.StatusResolved),
+			Resolution: sql.NullString{String: string(resolution), Valid: true},
+			Note:       request.Note,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return recordModeration(r.Context(), q, moderatorID, modActionResolveReport, resolved, resolved.ChirpID, string(resolution))
+	})
+
+	if errors.Is(err, errNoChirp) {
+		err = marshalHelper(w, errResponse{Error: "Report isn't about a chirp"}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("resolve report: %v", err)
+		}
+		return
+	}
+
+	if writeReportStateError(w, err) || writeSanctionError(w, err) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to resolve report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if deleted > 0 {
+		cfg.emitEvent(r.Context(), webhooks.ChirpDeleted, resolved.UserID, chirpDeletedEvent{ID: resolved.ChirpID.UUID, UserID: resolved.UserID})
+	}
+
+	if suspended {
+		cfg.publish(realtimeSuspended, resolved.UserID, nil)
+	}
+
+	err = marshalHelper(w, reportToResponse(resolved), http.StatusOK)
+	if err != nil {
+		fmt.Printf("resolve report: %v", err)
+	}
+}
+
+// dismissReportHandler closes a claimed report without acting on it
+func (cfg *apiConfig) dismissReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	moderatorID := staffUser(r.Context()).ID
+
+	var request dismissReportParams
+
+	//the body is optional
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&request)
+	}
+
+	request.Note = strings.TrimSpace(request.Note)
+
+	if err == nil && utf8.RuneCountInString(request.Note) > maxModerationNoteLength {
+		err = fmt.Errorf("note is longer than %d characters", maxModerationNoteLength)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("dismiss report: %v", err)
+		}
+		return
+	}
+
+	var dismissed database.Report
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		stored, err := q.GetReportForUpdate(r.Context(), reportID)
+
+		if err != nil {
+			return err
+		}
+
+		err = report.CheckClose(report.Status(stored.Status), stored.ClaimedBy, moderatorID)
+
+		if err != nil {
+			return err
+		}
+
+		dismissed, err = q.CloseReport(r.Context(), database.CloseReportParams{
+			ID:     stored.ID,
+			Status: string(report.StatusDismissed),
+			Note:   request.Note,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return recordModeration(r.Context(), q, moderatorID, modActionDismissReport, dismissed, dismissed.ChirpID, request.Note)
+	})
+
+	if writeReportStateError(w, err) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to dismiss report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, reportToResponse(dismissed), http.StatusOK)
+	if err != nil {
+		fmt.Printf("dismiss report: %v", err)
+	}
+}
+
+// moderationActionsHandler is the audit trail of every moderator action,
+// newest first, optionally only those against ?user_id=
+func (cfg *apiConfig) moderationActionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	pos, limit, err := parsePage(r)
+
+	var userID uuid.NullUUID
+
+	if raw := r.URL.Query().Get("user_id"); err == nil && raw != "" {
+		userID.UUID, err = uuid.Parse(raw)
+		userID.Valid = err == nil
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("moderation actions: %v", err)
+		}
+		return
+	}
+
+	actions, err := cfg.db.ListModerationActions(r.Context(), database.ListModerationActionsParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive moderation actions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := moderationActionPageResponse{
+		Actions: []moderationActionResponse{},
+	}
+
+	for _, action := range actions {
+		res.Actions = append(res.Actions, moderationActionToResponse(action))
+	}
+
+	if len(actions) == int(limit) {
+		last := actions[len(actions)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("moderation actions: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/roles"
+	"github.com/google/uuid"
+)
+
+const modActionChangeRole = "user.role_changed"
+
+type staffKey struct{}
+
+type setRoleParams struct {
+	Role string `json:"role"`
+}
+
+type roleResponse struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+// middlewarePermission only lets users whose role has perm through, and
+// passes the user on to the handler, see staffUser. Suspended staff are
+// turned away like everyone else.
+func (cfg *apiConfig) middlewarePermission(perm roles.Permission, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearerToken, err := auth.GetBearerToken(r.Header)
+
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			log.Printf("Unable to retrieve Bearer token: %v", err)
+			return
+		}
+
+		userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			log.Printf("Failed to validate user: %v", err)
+			return
+		}
+
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+		if err != nil {
+			log.Printf("Failed to retreive user: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if !roles.Role(user.Role).Can(perm) {
+			log.Printf("User %v (%v) denied %v on %v", user.ID, user.Role, perm, r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		suspension, suspended, err := cfg.activeSuspension(r.Context(), user.ID)
+
+		if err != nil {
+			log.Printf("Failed to check suspension: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if suspended {
+			writeSuspended(w, suspension, "admin")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), staffKey{}, user)))
+	})
+}
+
+// staffUser is the user middlewarePermission let through
+func staffUser(ctx context.Context) database.User {
+	user, _ := ctx.Value(staffKey{}).(database.User)
+	return user
+}
+
+// setRoleHandler makes a user a moderator or an admin, or takes that away.
+// Admins can't change their own role, so there is always one left.
+func (cfg *apiConfig) setRoleHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	actor := staffUser(r.Context())
+
+	var request setRoleParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	var role roles.Role
+
+	if err == nil {
+		role, err = roles.Parse(request.Role)
+	}
+
+	if err == nil && userID == actor.ID {
+		err = fmt.Errorf("You can't change your own role")
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("set role: %v", err)
+		}
+		return
+	}
+
+	var user database.User
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+
+		user, err = q.SetUserRole(r.Context(), database.SetUserRoleParams{
+			ID:   userID,
+			Role: string(role),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ActorID: actor.ID,
+			Action:  modActionChangeRole,
+			UserID:  uuid.NullUUID{UUID: user.ID, Valid: true},
+			Note:    string(role),
+		})
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to set role: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, roleResponse{UserID: user.ID, Role: user.Role}, http.StatusOK)
+	if err != nil {
+		fmt.Printf("set role: %v", err)
+	}
+}
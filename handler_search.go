@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/search"
+	"github.com/google/uuid"
+)
+
+// search results can't be keyset paginated when ordered by relevance, so
+// the offset is capped to keep deep pages from scanning every match
+const maxSearchOffset = 1000
+
+type searchResultResponse struct {
+	Chirp   chirpResponse `json:"chirp"`
+	Rank    float64       `json:"rank"`
+	Snippet string        `json:"snippet"`
+}
+
+type searchResponse struct {
+	Results    []searchResultResponse `json:"results"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+
+	queryParams := r.URL.Query()
+
+	opts, err := cfg.parseSearchOptions(r)
+
+	if err == nil && strings.TrimSpace(queryParams.Get("q")) == "" {
+		err = fmt.Errorf("q is required")
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("search chirps: %v", err)
+		}
+		return
+	}
+
+	results, err := cfg.searcher.Search(r.Context(), queryParams.Get("q"), opts)
+
+	if err != nil {
+		log.Printf("Failed to search chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), cfg.optionalViewer(r))
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := searchResponse{
+		Results: []searchResultResponse{},
+	}
+
+	//the next offset counts hidden results, a page may come back short
+	var visible []search.Result
+	var chirps []database.Chirp
+
+	for _, result := range results {
+		if hidden[result.Chirp.UserID] {
+			continue
+		}
+
+		visible = append(visible, result)
+		chirps = append(chirps, result.Chirp)
+	}
+
+	for i, chirp := range cfg.chirpsToResponse(r.Context(), chirps) {
+		res.Results = append(res.Results, searchResultResponse{
+			Chirp:   chirp,
+			Rank:    visible[i].Rank,
+			Snippet: visible[i].Snippet,
+		})
+	}
+
+	if len(results) == opts.Limit && opts.Offset+opts.Limit <= maxSearchOffset {
+		res.NextCursor = cursor.EncodeOffset(opts.Offset + opts.Limit)
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("search chirps: %v", err)
+	}
+}
+
+// parseSearchOptions reads author_id or author=@handle, since/until
+// (RFC 3339), order (relevance or recency), limit and cursor
+func (cfg *apiConfig) parseSearchOptions(r *http.Request) (search.Options, error) {
+
+	queryParams := r.URL.Query()
+
+	opts := search.Options{
+		Order: search.OrderRelevance,
+		Limit: defaultPageSize,
+	}
+
+	if order := queryParams.Get("order"); order != "" {
+		if order != string(search.OrderRelevance) && order != string(search.OrderRecency) {
+			return opts, fmt.Errorf("order must be relevance or recency")
+		}
+		opts.Order = search.Order(order)
+	}
+
+	if authorID := queryParams.Get("author_id"); authorID != "" {
+		parsedID, err := uuid.Parse(authorID)
+
+		if err != nil {
+			return opts, fmt.Errorf("invalid author_id: %w", err)
+		}
+
+		opts.AuthorID = parsedID
+	} else if author := queryParams.Get("author"); author != "" {
+		user, err := cfg.resolveUserRef(r, author)
+
+		if err != nil {
+			return opts, fmt.Errorf("unknown author: %v", author)
+		}
+
+		opts.AuthorID = user.ID
+	}
+
+	for param, dst := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		raw := queryParams.Get(param)
+
+		if raw == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, raw)
+
+		if err != nil {
+			return opts, fmt.Errorf("invalid %v, expected RFC 3339: %w", param, err)
+		}
+
+		*dst = t
+	}
+
+	if rawLimit := queryParams.Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("invalid limit: %q", rawLimit)
+		}
+
+		opts.Limit = min(limit, maxPageSize)
+	}
+
+	offset, err := cursor.DecodeOffset(queryParams.Get("cursor"))
+
+	if err != nil {
+		return opts, err
+	}
+
+	if offset > maxSearchOffset {
+		return opts, fmt.Errorf("cursor is past the last page of results")
+	}
+
+	opts.Offset = offset
+
+	return opts, nil
+}
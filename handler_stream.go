@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/stream"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+// event types pushed on the chirp stream
+var chirpStreamEvents = map[string]bool{
+	webhooks.ChirpCreated: true,
+	webhooks.ChirpDeleted: true,
+}
+
+// chirpStreamHandler pushes new and deleted chirps as Server-Sent Events.
+// Reconnecting clients send Last-Event-ID and get what they missed from the
+// broker's replay buffer, or a reset event when that isn't possible.
+func (cfg *apiConfig) chirpStreamHandler(w http.ResponseWriter, r *http.Request) {
+
+	var authorID uuid.UUID
+
+	if rawAuthorID := r.URL.Query().Get("author_id"); rawAuthorID != "" {
+		var err error
+		authorID, err = uuid.Parse(rawAuthorID)
+
+		if err != nil {
+			err = marshalHelper(w, errResponse{Error: "invalid author_id"}, http.StatusBadRequest)
+			if err != nil {
+				fmt.Printf("chirp stream: %v", err)
+			}
+			return
+		}
+	}
+
+	//signed in clients don't get chirps of users they muted or are blocked
+	//with, as of when they connected
+	hidden, err := cfg.hiddenUsersFor(r.Context(), cfg.optionalViewer(r))
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	match := func(event stream.Event) bool {
+		return chirpStreamEvents[event.Type] && (authorID == uuid.Nil || event.SubjectID == authorID) && !hidden[event.SubjectID]
+	}
+
+	//EventSource sends Last-Event-ID itself, other clients may use the query param
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, replay, complete := cfg.broker.Subscribe(lastEventID, match)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+
+	//the stream outlives any server write timeout
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	//events may have been missed, the client should refetch GET /api/chirps
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	for _, event := range replay {
+		writeStreamEvent(w, event)
+	}
+
+	if err := rc.Flush(); err != nil {
+		log.Printf("chirp stream: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-sub.C:
+			//dropped for falling behind, the client resumes with Last-Event-ID
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, event)
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event stream.Event) {
+	fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/entitlements"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// billing period assumed when Polka doesn't send one
+const subscriptionPeriod = 30 * 24 * time.Hour
+
+// how long past the period end a membership survives before the sweep
+// expires it, so a renewal webhook that arrives late doesn't drop perks
+const subscriptionGrace = 24 * time.Hour
+
+const (
+	polkaUpgraded   = "user.upgraded"
+	polkaRenewed    = "user.renewed"
+	polkaCanceled   = "user.canceled"
+	polkaDowngraded = "user.downgraded"
+)
+
+const subscriptionExpired = "expired"
+
+var polkaEvents = map[string]bool{
+	polkaUpgraded:   true,
+	polkaRenewed:    true,
+	polkaCanceled:   true,
+	polkaDowngraded: true,
+}
+
+type subscriptionResponse struct {
+	Plan               string     `json:"plan"`
+	Status             string     `json:"status"`
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `json:"current_period_end"`
+	CanceledAt         *time.Time `json:"canceled_at"`
+}
+
+type planChangeResponse struct {
+	Event     string     `json:"event"`
+	FromPlan  string     `json:"from_plan"`
+	ToPlan    string     `json:"to_plan"`
+	PeriodEnd *time.Time `json:"period_end"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type planHistoryResponse struct {
+	Plan         entitlements.Plan     `json:"plan"`
+	Subscription *subscriptionResponse `json:"subscription"`
+	History      []planChangeResponse  `json:"history"`
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// applyPolkaEvent updates the membership flag, the subscription and the
+// plan history together. periodEnd is optional. Returns sql.ErrNoRows for
+// unknown users.
+func (cfg *apiConfig) applyPolkaEvent(ctx context.Context, event string, userID uuid.UUID, periodEnd time.Time) error {
+
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+
+		user, err := q.GetUserByID(ctx, userID)
+
+		if err != nil {
+			return err
+		}
+
+		change := database.CreatePlanChangeParams{
+			UserID:   userID,
+			Event:    event,
+			FromPlan: string(entitlements.PlanOf(user.IsChirpRed)),
+		}
+
+		switch event {
+		case polkaUpgraded, polkaRenewed:
+			start := time.Now()
+
+			//renewing early extends the current period instead of cutting it short
+			sub, err := q.GetSubscription(ctx, userID)
+			if err == nil && event == polkaRenewed && sub.Status != subscriptionExpired && sub.CurrentPeriodEnd.After(start) {
+				start = sub.CurrentPeriodEnd
+			}
+
+			end := periodEnd
+			if end.IsZero() {
+				end = start.Add(subscriptionPeriod)
+			}
+
+			sub, err = q.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+				UserID:             userID,
+				Plan:               string(entitlements.PlanChirpyRed),
+				CurrentPeriodStart: start,
+				CurrentPeriodEnd:   end,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if !user.IsChirpRed {
+				_, err = q.UpgradeChirpRed(ctx, userID)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			change.ToPlan = sub.Plan
+			change.PeriodEnd = sql.NullTime{Time: sub.CurrentPeriodEnd, Valid: true}
+
+		case polkaCanceled:
+			sub, err := q.CancelSubscription(ctx, userID)
+
+			if errors.Is(err, sql.ErrNoRows) {
+				if !user.IsChirpRed {
+					//nothing active to cancel, e.g. a repeated webhook
+					return nil
+				}
+
+				//memberships from before subscriptions were tracked have no
+				//period end to run until
+				_, err = q.DowngradeChirpRed(ctx, userID)
+
+				if err != nil {
+					return err
+				}
+
+				change.ToPlan = string(entitlements.PlanFree)
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			change.ToPlan = change.FromPlan
+			change.PeriodEnd = sql.NullTime{Time: sub.CurrentPeriodEnd, Valid: true}
+
+		case polkaDowngraded:
+			_, err = q.DowngradeChirpRed(ctx, userID)
+
+			if err != nil {
+				return err
+			}
+
+			err = q.EndSubscription(ctx, userID)
+
+			if err != nil {
+				return err
+			}
+
+			change.ToPlan = string(entitlements.PlanFree)
+		}
+
+		err = q.CreatePlanChange(ctx, change)
+
+		if err != nil {
+			return err
+		}
+
+		if change.ToPlan == change.FromPlan {
+			return nil
+		}
+
+		//queued in the transaction, so only sent if the change is committed
+		eventType := webhooks.UserUpgraded
+		if change.ToPlan == string(entitlements.PlanFree) {
+			eventType = webhooks.UserDowngraded
+		}
+
+		return webhooks.Emit(ctx, webhooks.Postgres{DB: q}, eventType, userID, planChangedEvent{UserID: userID, Plan: change.ToPlan})
+	})
+}
+
+// expireSubscriptions downgrades lapsed memberships every interval until
+// ctx is done
+func (cfg *apiConfig) expireSubscriptions(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := cfg.db.ExpireSubscriptions(ctx, time.Now().Add(-subscriptionGrace))
+
+		if err != nil {
+			log.Printf("Failed to expire subscriptions: %v", err)
+		} else if len(expired) > 0 {
+			log.Printf("Expired %d Chirpy Red memberships", len(expired))
+		}
+
+		for _, userID := range expired {
+			cfg.emitEvent(ctx, webhooks.UserDowngraded, userID, planChangedEvent{UserID: userID, Plan: string(entitlements.PlanFree)})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// planHistoryHandler shows support a user's subscription and every change
+// to their plan, newest first
+func (cfg *apiConfig) planHistoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive user: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	res := planHistoryResponse{
+		Plan:    entitlements.PlanOf(user.IsChirpRed),
+		History: []planChangeResponse{},
+	}
+
+	sub, err := cfg.db.GetSubscription(r.Context(), userID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to retreive subscription: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil {
+		res.Subscription = &subscriptionResponse{
+			Plan:               sub.Plan,
+			Status:             sub.Status,
+			CurrentPeriodStart: sub.CurrentPeriodStart,
+			CurrentPeriodEnd:   sub.CurrentPeriodEnd,
+			CanceledAt:         nullTimePtr(sub.CanceledAt),
+		}
+	}
+
+	changes, err := cfg.db.GetPlanChanges(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive plan history: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for _, change := range changes {
+		res.History = append(res.History, planChangeResponse{
+			Event:     change.Event,
+			FromPlan:  change.FromPlan,
+			ToPlan:    change.ToPlan,
+			PeriodEnd: nullTimePtr(change.PeriodEnd),
+			CreatedAt: change.CreatedAt,
+		})
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		log.Printf("plan history: %v", err)
+	}
+}
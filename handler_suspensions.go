@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/roles"
+	"github.com/google/uuid"
+)
+
+const errCodeSuspended = "account_suspended"
+
+const modActionUnsuspendUser = "user.unsuspended"
+
+var (
+	errCantSanction = errors.New("only admins can sanction staff")
+	errNotSuspended = errors.New("user isn't suspended")
+)
+
+// activeSuspension returns the suspension keeping a user from logging in
+// and posting, ok is false when there is none
+func (cfg *apiConfig) activeSuspension(ctx context.Context, userID uuid.UUID) (suspension database.Suspension, ok bool, err error) {
+
+	suspension, err = cfg.db.GetActiveSuspension(ctx, userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Suspension{}, false, nil
+	}
+
+	if err != nil {
+		return database.Suspension{}, false, err
+	}
+
+	return suspension, true, nil
+}
+
+// suspensionMessage tells a suspended user why and for how long
+func suspensionMessage(suspension database.Suspension) string {
+
+	if !suspension.ExpiresAt.Valid {
+		return "Your account is suspended: " + suspension.Reason
+	}
+
+	return fmt.Sprintf("Your account is suspended until %v: %v", suspension.ExpiresAt.Time.UTC().Format(time.RFC3339), suspension.Reason)
+}
+
+// writeSuspended answers a suspended user with 403
+func writeSuspended(w http.ResponseWriter, suspension database.Suspension, context string) {
+
+	err := marshalHelper(w, errResponse{Error: suspensionMessage(suspension), Code: errCodeSuspended}, http.StatusForbidden)
+	if err != nil {
+		fmt.Printf("%s: %v", context, err)
+	}
+}
+
+type suspendParams struct {
+	Reason string `json:"reason"`
+	//suspensions without an end last until lifted
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type unsuspendParams struct {
+	Reason string `json:"reason"`
+}
+
+type suspensionResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Reason     string     `json:"reason"`
+	ReportID   *uuid.UUID `json:"report_id"`
+	CreatedBy  *uuid.UUID `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LiftedAt   *time.Time `json:"lifted_at"`
+	LiftedBy   *uuid.UUID `json:"lifted_by"`
+	LiftReason string     `json:"lift_reason"`
+	Active     bool       `json:"active"`
+}
+
+type suspensionPageResponse struct {
+	Suspensions []suspensionResponse `json:"suspensions"`
+	NextCursor  string               `json:"next_cursor,omitempty"`
+}
+
+func suspensionToResponse(suspension database.Suspension, now time.Time) suspensionResponse {
+
+	return suspensionResponse{
+		ID:         suspension.ID,
+		UserID:     suspension.UserID,
+		Reason:     suspension.Reason,
+		ReportID:   nullUUIDPtr(suspension.ReportID),
+		CreatedBy:  nullUUIDPtr(suspension.CreatedBy),
+		CreatedAt:  suspension.CreatedAt,
+		ExpiresAt:  nullTimePtr(suspension.ExpiresAt),
+		LiftedAt:   nullTimePtr(suspension.LiftedAt),
+		LiftedBy:   nullUUIDPtr(suspension.LiftedBy),
+		LiftReason: suspension.LiftReason,
+		Active:     !suspension.LiftedAt.Valid && (!suspension.ExpiresAt.Valid || suspension.ExpiresAt.Time.After(now)),
+	}
+}
+
+// checkSanction loads the user a moderator is about to suspend, warn or
+// unsuspend and makes sure the moderator may
+func checkSanction(ctx context.Context, q *database.Queries, actor database.User, userID uuid.UUID) error {
+
+	target, err := q.GetUserByID(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if !roles.Role(actor.Role).CanSanction(roles.Role(target.Role)) {
+		return errCantSanction
+	}
+
+	return nil
+}
+
+// parseModerationNote trims a moderator's note or reason and checks its
+// length, required notes can't be empty
+func parseModerationNote(note string, required bool) (string, error) {
+
+	note = strings.TrimSpace(note)
+
+	if utf8.RuneCountInString(note) > maxModerationNoteLength {
+		return "", fmt.Errorf("note is longer than %d characters", maxModerationNoteLength)
+	}
+
+	if required && note == "" {
+		return "", errors.New("A reason is required, the user is shown it")
+	}
+
+	return note, nil
+}
+
+// suspendUserHandler suspends a user until expires_at, or until lifted.
+// Suspended users can't log in, refresh their session, post or edit.
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	actor := staffUser(r.Context())
+
+	var request suspendParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err == nil {
+		request.Reason, err = parseModerationNote(request.Reason, true)
+	}
+
+	if err == nil && request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		err = errors.New("expires_at must be in the future")
+	}
+
+	if err == nil && userID == actor.ID {
+		err = errors.New("You can't suspend yourself")
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("suspend user: %v", err)
+		}
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if request.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: request.ExpiresAt.UTC(), Valid: true}
+	}
+
+	var suspension database.Suspension
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		err := checkSanction(r.Context(), q, actor, userID)
+
+		if err != nil {
+			return err
+		}
+
+		suspension, err = q.CreateSuspension(r.Context(), database.CreateSuspensionParams{
+			UserID:    userID,
+			Reason:    request.Reason,
+			CreatedBy: uuid.NullUUID{UUID: actor.ID, Valid: true},
+			ExpiresAt: expiresAt,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ActorID: actor.ID,
+			Action:  modActionSuspendUser,
+			UserID:  uuid.NullUUID{UUID: userID, Valid: true},
+			Note:    request.Reason,
+		})
+	})
+
+	if writeSanctionError(w, err) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to suspend user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//open websockets of the user are closed
+	cfg.publish(realtimeSuspended, userID, nil)
+
+	err = marshalHelper(w, suspensionToResponse(suspension, time.Now()), http.StatusCreated)
+	if err != nil {
+		fmt.Printf("suspend user: %v", err)
+	}
+}
+
+// unsuspendUserHandler lifts every active suspension of a user
+func (cfg *apiConfig) unsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	actor := staffUser(r.Context())
+
+	var request unsuspendParams
+
+	//the body is optional
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&request)
+	}
+
+	if err == nil {
+		request.Reason, err = parseModerationNote(request.Reason, false)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("unsuspend user: %v", err)
+		}
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		err := checkSanction(r.Context(), q, actor, userID)
+
+		if err != nil {
+			return err
+		}
+
+		lifted, err := q.LiftSuspensions(r.Context(), database.LiftSuspensionsParams{
+			UserID:     userID,
+			LiftedBy:   uuid.NullUUID{UUID: actor.ID, Valid: true},
+			LiftReason: request.Reason,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if lifted == 0 {
+			return errNotSuspended
+		}
+
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ActorID: actor.ID,
+			Action:  modActionUnsuspendUser,
+			UserID:  uuid.NullUUID{UUID: userID, Valid: true},
+			Note:    request.Reason,
+		})
+	})
+
+	if writeSanctionError(w, err) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to unsuspend user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// suspensionsHandler lists a user's suspensions, lifted and expired ones
+// included, newest first
+func (cfg *apiConfig) suspensionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("suspensions: %v", err)
+		}
+		return
+	}
+
+	suspensions, err := cfg.db.GetSuspensions(r.Context(), database.GetSuspensionsParams{
+		UserID:          userID,
+		BeforeCreatedAt: pos.CreatedAt,
+		BeforeID:        pos.ID,
+		PageSize:        limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive suspensions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := suspensionPageResponse{
+		Suspensions: []suspensionResponse{},
+	}
+
+	now := time.Now()
+	for _, suspension := range suspensions {
+		res.Suspensions = append(res.Suspensions, suspensionToResponse(suspension, now))
+	}
+
+	if len(suspensions) == int(limit) {
+		last := suspensions[len(suspensions)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("suspensions: %v", err)
+	}
+}
+
+// writeSanctionError answers the errors of suspending, warning and
+// unsuspending, it returns false for any other error
+func writeSanctionError(w http.ResponseWriter, err error) bool {
+
+	var message string
+	status := http.StatusForbidden
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	case errors.Is(err, errCantSanction):
+		message = "Only admins can sanction moderators and admins"
+	case errors.Is(err, errNotSuspended):
+		message, status = "User isn't suspended", http.StatusConflict
+	default:
+		return false
+	}
+
+	err = marshalHelper(w, errResponse{Error: message}, status)
+	if err != nil {
+		fmt.Printf("sanction: %v", err)
+	}
+
+	return true
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const polkaSource = "polka"
+
+// maxPolkaWebhookBytes caps Polka requests, events are a few hundred bytes
+const maxPolkaWebhookBytes = 64 << 10
+
+const (
+	webhookProcessing = "processing"
+	webhookProcessed  = "processed"
+	webhookIgnored    = "ignored"
+	webhookFailed     = "failed"
+)
+
+var webhookStatuses = map[string]bool{
+	webhookProcessing: true,
+	webhookProcessed:  true,
+	webhookIgnored:    true,
+	webhookFailed:     true,
+}
+
+type webhookEventResponse struct {
+	ID             uuid.UUID       `json:"id"`
+	Source         string          `json:"source"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	ResponseStatus int32           `json:"response_status"`
+	Error          string          `json:"error"`
+	Attempts       int32           `json:"attempts"`
+	ReceivedAt     time.Time       `json:"received_at"`
+	ProcessedAt    *time.Time      `json:"processed_at"`
+	StartedAt      time.Time       `json:"started_at"`
+}
+
+type webhookEventPageResponse struct {
+	Events     []webhookEventResponse `json:"events"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+func webhookEventToResponse(event database.WebhookEvent) webhookEventResponse {
+
+	res := webhookEventResponse{
+		ID:             event.ID,
+		Source:         event.Source,
+		EventID:        event.EventID,
+		EventType:      event.EventType,
+		Payload:        json.RawMessage(event.Payload),
+		Status:         event.Status,
+		ResponseStatus: event.ResponseStatus,
+		Error:          event.Error,
+		Attempts:       event.Attempts,
+		ReceivedAt:     event.ReceivedAt,
+		ProcessedAt:    nullTimePtr(event.ProcessedAt),
+		StartedAt:      event.StartedAt,
+	}
+
+	//payloads are logged as received and may not be valid JSON
+	if !json.Valid(res.Payload) {
+		res.Payload, _ = json.Marshal(event.Payload)
+	}
+
+	return res
+}
+
+// polkaEventID prefers the id Polka sends, falling back to a hash of the
+// payload so byte-identical retries still match
+func polkaEventID(request isChirpRedWebhookRequest, payload []byte) string {
+
+	if request.ID != "" {
+		return request.ID
+	}
+
+	sum := sha256.Sum256(payload)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// verifyPolkaRequest checks the signature over the raw body. Unsigned
+// requests fall back to the ApiKey only when compatibility mode is on.
+func (cfg *apiConfig) verifyPolkaRequest(headers http.Header, payload []byte) error {
+
+	signature := headers.Get(auth.WebhookSignatureHeader)
+
+	if signature == "" && cfg.polkaAllowAPIKey {
+		return auth.CheckAPIKey(headers, cfg.polkaKey)
+	}
+
+	return auth.VerifyWebhookSignature(signature, payload, cfg.polkaSecrets, cfg.polkaTolerance, time.Now())
+}
+
+// processPolkaEvent applies a logged event and records the outcome, the
+// returned event's ResponseStatus is what Polka gets answered with
+func (cfg *apiConfig) processPolkaEvent(ctx context.Context, event database.WebhookEvent) (database.WebhookEvent, error) {
+
+	result := database.FinishWebhookEventParams{
+		ID:             event.ID,
+		Status:         webhookProcessed,
+		ResponseStatus: http.StatusNoContent,
+	}
+
+	var request isChirpRedWebhookRequest
+
+	err := json.Unmarshal([]byte(event.Payload), &request)
+
+	if err == nil && !polkaEvents[request.Event] {
+		result.Status = webhookIgnored
+	}
+
+	if err == nil && result.Status != webhookIgnored {
+		var userID uuid.UUID
+
+		userID, err = uuid.Parse(request.Data.UserID)
+
+		if err == nil {
+			err = cfg.applyPolkaEvent(ctx, request.Event, userID, request.Data.PeriodEnd)
+		}
+	}
+
+	if err != nil {
+		log.Printf("Failed to apply %v webhook %v: %v", event.Source, event.EventID, err)
+
+		result.Status = webhookFailed
+		result.ResponseStatus = http.StatusInternalServerError
+		result.Error = err.Error()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			result.ResponseStatus = http.StatusNotFound
+		}
+	}
+
+	return cfg.db.FinishWebhookEvent(ctx, result)
+}
+
+func (cfg *apiConfig) webhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+
+	pos, limit, err := parsePage(r)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("webhook events: %v", err)
+		}
+		return
+	}
+
+	var status sql.NullString
+
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		if !webhookStatuses[raw] {
+			err = marshalHelper(w, errResponse{Error: fmt.Sprintf("unknown status %q", raw)}, http.StatusBadRequest)
+			if err != nil {
+				fmt.Printf("webhook events: %v", err)
+			}
+			return
+		}
+
+		status = sql.NullString{String: raw, Valid: true}
+	}
+
+	events, err := cfg.db.ListWebhookEvents(r.Context(), database.ListWebhookEventsParams{
+		Status:           status,
+		BeforeReceivedAt: pos.CreatedAt,
+		BeforeID:         pos.ID,
+		PageSize:         limit,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive webhook events: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := webhookEventPageResponse{
+		Events: []webhookEventResponse{},
+	}
+
+	for _, event := range events {
+		res.Events = append(res.Events, webhookEventToResponse(event))
+	}
+
+	if len(events) == int(limit) {
+		last := events[len(events)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.ReceivedAt, ID: last.ID}.Encode()
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("webhook events: %v", err)
+	}
+}
+
+// replayWebhookEventHandler processes a failed event again, e.g. after the
+// user it refers to was restored, or one left processing past its lease
+func (cfg *apiConfig) replayWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	event, err := cfg.db.StartWebhookEventReplay(r.Context(), database.StartWebhookEventReplayParams{
+		ID:          eventID,
+		StaleBefore: webhooks.StaleBefore(time.Now(), webhooks.InboundLease),
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		event, err = cfg.db.GetWebhookEvent(r.Context(), eventID)
+
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("Only failed or abandoned events can be replayed, event is %v", event.Status)}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("replay webhook event: %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to claim webhook event: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	event, err = cfg.processPolkaEvent(r.Context(), event)
+
+	if err != nil {
+		log.Printf("Failed to record webhook replay: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = marshalHelper(w, webhookEventToResponse(event), http.StatusOK)
+	if err != nil {
+		fmt.Printf("replay webhook event: %v", err)
+	}
+}
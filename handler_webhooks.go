@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/netguard"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const maxWebhookEndpoints = 10
+const webhookDeliveriesPageSize = 50
+
+type webhookEndpointParams struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type webhookEndpointResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	//only returned when the endpoint is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type webhookAttemptResponse struct {
+	Attempt      int32     `json:"attempt"`
+	StatusCode   int32     `json:"status_code"`
+	Error        string    `json:"error"`
+	ResponseBody string    `json:"response_body"`
+	DurationMs   int32     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type webhookDeliveryResponse struct {
+	ID            uuid.UUID                `json:"id"`
+	EventID       uuid.UUID                `json:"event_id"`
+	EventType     string                   `json:"event_type"`
+	Status        string                   `json:"status"`
+	NextAttemptAt *time.Time               `json:"next_attempt_at"`
+	CreatedAt     time.Time                `json:"created_at"`
+	CompletedAt   *time.Time               `json:"completed_at"`
+	Attempts      []webhookAttemptResponse `json:"attempts"`
+}
+
+type chirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type planChangedEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Plan   string    `json:"plan"`
+}
+
+func webhookEndpointToResponse(endpoint database.WebhookEndpoint) webhookEndpointResponse {
+	return webhookEndpointResponse{
+		ID:                  endpoint.ID,
+		URL:                 endpoint.Url,
+		Events:              endpoint.Events,
+		Enabled:             endpoint.Enabled,
+		ConsecutiveFailures: endpoint.ConsecutiveFailures,
+		DisabledAt:          nullTimePtr(endpoint.DisabledAt),
+		CreatedAt:           endpoint.CreatedAt,
+	}
+}
+
+// emitEvent publishes a domain event about subjectID (a chirp's author or
+// the user concerned) to streaming clients and queues it for outbound
+// webhooks. Failing to emit one never fails the request that caused it.
+func (cfg *apiConfig) emitEvent(ctx context.Context, eventType string, subjectID uuid.UUID, data any) {
+
+	err := cfg.broker.Publish(eventType, subjectID, data)
+
+	if err != nil {
+		log.Printf("Failed to publish %v event: %v", eventType, err)
+	}
+
+	err = webhooks.Emit(ctx, webhooks.Postgres{DB: cfg.db}, eventType, subjectID, data)
+
+	if err != nil {
+		log.Printf("Failed to queue %v webhooks: %v", eventType, err)
+	}
+}
+
+func (cfg *apiConfig) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var request webhookEndpointParams
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err == nil {
+		err = validateWebhookEndpoint(request, cfg.webhooksAllowPrivate)
+	}
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("create webhook: %v", err)
+		}
+		return
+	}
+
+	existing, err := cfg.db.GetWebhookEndpoints(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive webhook endpoints: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(existing) >= maxWebhookEndpoints {
+		err = marshalHelper(w, errResponse{Error: fmt.Sprintf("A user can register up to %d webhook endpoints", maxWebhookEndpoints)}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("create webhook: %v", err)
+		}
+		return
+	}
+
+	secret, err := auth.MakeRefreshToken()
+
+	if err != nil {
+		log.Printf("Failed to generate webhook secret: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID: userID,
+		Url:    request.URL,
+		Secret: "whsec_" + secret,
+		Events: request.Events,
+	})
+
+	if err != nil {
+		log.Printf("Failed to create webhook endpoint: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := webhookEndpointToResponse(endpoint)
+	res.Secret = endpoint.Secret
+
+	err = marshalHelper(w, res, http.StatusCreated)
+	if err != nil {
+		fmt.Printf("create webhook: %v", err)
+	}
+}
+
+// validateWebhookEndpoint turns away URLs that obviously point inside our
+// network, the worker's client also refuses internal addresses once they
+// are resolved
+func validateWebhookEndpoint(request webhookEndpointParams, allowPrivate bool) error {
+
+	parsed, err := url.Parse(request.URL)
+
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+
+	if !allowPrivate {
+		host := strings.ToLower(parsed.Hostname())
+
+		if ip, err := netip.ParseAddr(host); err == nil && netguard.Blocked(ip) {
+			return fmt.Errorf("url must not point to a private or internal address")
+		}
+
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return fmt.Errorf("url must not point to a private or internal address")
+		}
+
+		if port := parsed.Port(); port != "" && port != map[string]string{"http": "80", "https": "443"}[parsed.Scheme] {
+			return fmt.Errorf("url must use the default port for %v", parsed.Scheme)
+		}
+	}
+
+	if len(request.Events) == 0 {
+		return fmt.Errorf("subscribe to at least one of %v", webhooks.EventTypes)
+	}
+
+	for _, event := range request.Events {
+		if !slices.Contains(webhooks.EventTypes, event) {
+			return fmt.Errorf("unknown event %q, expected one of %v", event, webhooks.EventTypes)
+		}
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	endpoints, err := cfg.db.GetWebhookEndpoints(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive webhook endpoints: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res := []webhookEndpointResponse{}
+
+	for _, endpoint := range endpoints {
+		res = append(res, webhookEndpointToResponse(endpoint))
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("list webhooks: %v", err)
+	}
+}
+
+func (cfg *apiConfig) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to delete webhook endpoint: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// enableWebhookHandler re-enables an endpoint that was disabled after
+// repeated failures, its queued deliveries are sent again
+func (cfg *apiConfig) enableWebhookHandler(w http.ResponseWriter, r *http.Request) {
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	endpoint, err := cfg.db.EnableWebhookEndpoint(r.Context(), database.EnableWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to enable webhook endpoint: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = marshalHelper(w, webhookEndpointToResponse(endpoint), http.StatusOK)
+	if err != nil {
+		fmt.Printf("enable webhook: %v", err)
+	}
+}
+
+// webhookDeliveriesHandler lists an endpoint's most recent deliveries with
+// the log of every attempt
+func (cfg *apiConfig) webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		log.Printf("missing bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_, err = cfg.db.GetWebhookEndpoint(r.Context(), database.GetWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	deliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		EndpointID: endpointID,
+		Limit:      webhookDeliveriesPageSize,
+	})
+
+	if err != nil {
+		log.Printf("Failed to retreive webhook deliveries: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	deliveryIDs := make([]uuid.UUID, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	attempts, err := cfg.db.GetWebhookDeliveryAttempts(r.Context(), deliveryIDs)
+
+	if err != nil {
+		log.Printf("Failed to retreive webhook attempts: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	attemptsByDelivery := map[uuid.UUID][]webhookAttemptResponse{}
+	for _, attempt := range attempts {
+		attemptsByDelivery[attempt.DeliveryID] = append(attemptsByDelivery[attempt.DeliveryID], webhookAttemptResponse{
+			Attempt:      attempt.Attempt,
+			StatusCode:   attempt.StatusCode,
+			Error:        attempt.Error,
+			ResponseBody: attempt.ResponseBody,
+			DurationMs:   attempt.DurationMs,
+			CreatedAt:    attempt.CreatedAt,
+		})
+	}
+
+	res := []webhookDeliveryResponse{}
+
+	for _, delivery := range deliveries {
+		item := webhookDeliveryResponse{
+			ID:          delivery.ID,
+			EventID:     delivery.EventID,
+			EventType:   delivery.EventType,
+			Status:      delivery.Status,
+			CreatedAt:   delivery.CreatedAt,
+			CompletedAt: nullTimePtr(delivery.CompletedAt),
+			Attempts:    attemptsByDelivery[delivery.ID],
+		}
+
+		if item.Attempts == nil {
+			item.Attempts = []webhookAttemptResponse{}
+		}
+
+		if delivery.Status == "pending" {
+			item.NextAttemptAt = &delivery.NextAttemptAt
+		}
+
+		res = append(res, item)
+	}
+
+	err = marshalHelper(w, res, http.StatusOK)
+	if err != nil {
+		fmt.Printf("webhook deliveries: %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/stream"
+	"github.com/JonMunkholm/server/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// realtime-only events, published to the broker but not to webhooks. The
+// subject is the follower for follows, the user whose blocks or mutes
+// changed for hidden.changed, the suspended user for user.suspended and the
+// recipient otherwise.
+const (
+	realtimeFollowCreated = "follow.created"
+	realtimeFollowDeleted = "follow.deleted"
+	realtimeMention       = "mention.created"
+	realtimeNotification  = "notification"
+	realtimeHiddenChanged = "hidden.changed"
+	realtimeSuspended     = "user.suspended"
+)
+
+// websocket channels a client can subscribe to, the protocol is documented
+// in README.md
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelMentions      = "mentions"
+	wsChannelNotifications = "notifications"
+)
+
+const (
+	wsWriteTimeout  = 10 * time.Second
+	wsPongTimeout   = 60 * time.Second
+	wsPingInterval  = 30 * time.Second
+	wsMaxMessageLen = 4096
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	//clients authenticate with a token, not cookies, so any origin is fine
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type followEvent struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+}
+
+// wsClientMessage is every message a client sends
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// wsServerMessage is every message the server sends
+type wsServerMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Channel string          `json:"channel,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	EventID string          `json:"event_id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// publish sends a realtime-only event to the broker
+func (cfg *apiConfig) publish(eventType string, subjectID uuid.UUID, data any) {
+
+	err := cfg.broker.Publish(eventType, subjectID, data)
+
+	if err != nil {
+		log.Printf("Failed to publish %v event: %v", eventType, err)
+	}
+}
+
+// wsHandler upgrades to a websocket carrying the caller's timeline, mentions
+// and notifications. Browsers can't set headers on websockets, so the token
+// may also be passed as ?token=. The connection is closed when the token
+// expires or the user is suspended.
+func (cfg *apiConfig) wsHandler(w http.ResponseWriter, r *http.Request) {
+
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		token = r.URL.Query().Get("token")
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	expiresAt, err := auth.JWTExpiry(token, cfg.secret)
+
+	if err != nil {
+		log.Printf("Failed to validate user: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	suspension, suspended, err := cfg.activeSuspension(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to check suspension: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if suspended {
+		writeSuspended(w, suspension, "websocket")
+		return
+	}
+
+	followees, err := cfg.db.GetFolloweeIDs(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive followees: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	hidden, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+
+	if err != nil {
+		//the upgrader already answered
+		log.Printf("websocket upgrade: %v", err)
+		return
+	}
+
+	defer conn.Close()
+
+	//events on the timeline come from the user and everyone they follow
+	timeline := map[uuid.UUID]bool{userID: true}
+	for _, followee := range followees {
+		timeline[followee] = true
+	}
+
+	//the filter runs on the publisher's goroutine, timeline and hidden
+	//change when the user follows, blocks or mutes someone while connected
+	var mu sync.Mutex
+
+	sub, _, _ := cfg.broker.Subscribe("", func(event stream.Event) bool {
+		switch event.Type {
+		case webhooks.ChirpCreated, webhooks.ChirpDeleted:
+			mu.Lock()
+			defer mu.Unlock()
+			return timeline[event.SubjectID] && !hidden[event.SubjectID]
+		default:
+			return event.SubjectID == userID
+		}
+	})
+	defer sub.Close()
+
+	incoming := make(chan wsClientMessage)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go wsReadLoop(conn, incoming, readErr, done)
+
+	subscribed := map[string]bool{}
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+
+	for {
+		var out []wsServerMessage
+
+		select {
+		case err := <-readErr:
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("websocket read: %v", err)
+			}
+			return
+
+		case msg := <-incoming:
+			out = append(out, handleWSMessage(msg, subscribed))
+
+		case event, ok := <-sub.C:
+			//the broker dropped us for falling behind, the client should
+			//reconnect and refetch what it missed
+			if !ok {
+				wsClose(conn, websocket.CloseTryAgainLater, "too slow, reconnect")
+				return
+			}
+
+			switch event.Type {
+			case realtimeFollowCreated, realtimeFollowDeleted:
+				var follow followEvent
+				if json.Unmarshal(event.Data, &follow) == nil {
+					mu.Lock()
+					timeline[follow.FolloweeID] = event.Type == realtimeFollowCreated
+					mu.Unlock()
+				}
+
+			case realtimeHiddenChanged:
+				refreshed, err := cfg.hiddenUsersFor(r.Context(), userID)
+
+				if err != nil {
+					log.Printf("Failed to retreive hidden users: %v", err)
+					wsClose(conn, websocket.CloseTryAgainLater, "reconnect")
+					return
+				}
+
+				mu.Lock()
+				hidden = refreshed
+				mu.Unlock()
+
+			case realtimeSuspended:
+				wsClose(conn, websocket.ClosePolicyViolation, "account suspended")
+				return
+			}
+
+			if channel := wsChannelFor(event); channel != "" && subscribed[channel] {
+				out = append(out, wsServerMessage{
+					Type:    "event",
+					Channel: channel,
+					Event:   event.Type,
+					EventID: event.ID,
+					Data:    event.Data,
+				})
+			}
+
+		case <-expiry.C:
+			wsClose(conn, websocket.ClosePolicyViolation, "session expired")
+			return
+
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+
+		for _, msg := range out {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+			if err := conn.WriteJSON(msg); err != nil {
+				log.Printf("websocket write: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// wsReadLoop reads client messages until the connection fails or done is
+// closed. A missing pong within wsPongTimeout counts as a failure.
+func wsReadLoop(conn *websocket.Conn, incoming chan<- wsClientMessage, readErr chan<- error, done <-chan struct{}) {
+
+	conn.SetReadLimit(wsMaxMessageLen)
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		var msg wsClientMessage
+
+		_, data, err := conn.ReadMessage()
+
+		if err != nil {
+			readErr <- err
+			return
+		}
+
+		if err := json.Unmarshal(data, &msg); err != nil {
+			msg = wsClientMessage{Type: "invalid"}
+		}
+
+		select {
+		case incoming <- msg:
+		case <-done:
+			return
+		}
+	}
+}
+
+func handleWSMessage(msg wsClientMessage, subscribed map[string]bool) wsServerMessage {
+
+	switch msg.Type {
+	case "ping":
+		return wsServerMessage{Type: "pong", ID: msg.ID}
+
+	case "subscribe", "unsubscribe":
+		switch msg.Channel {
+		case wsChannelTimeline, wsChannelMentions, wsChannelNotifications:
+		default:
+			return wsServerMessage{Type: "error", ID: msg.ID, Error: "unknown channel " + msg.Channel}
+		}
+
+		subscribed[msg.Channel] = msg.Type == "subscribe"
+
+		return wsServerMessage{Type: msg.Type + "d", ID: msg.ID, Channel: msg.Channel}
+	}
+
+	return wsServerMessage{Type: "error", ID: msg.ID, Error: "unknown message type " + msg.Type}
+}
+
+// wsChannelFor picks the channel an event is delivered on, or "" when it
+// isn't for the client. The subscription filter already dropped chirps off
+// the client's timeline.
+func wsChannelFor(event stream.Event) string {
+
+	switch event.Type {
+	case webhooks.ChirpCreated, webhooks.ChirpDeleted:
+		return wsChannelTimeline
+	case realtimeMention:
+		return wsChannelMentions
+	case realtimeNotification:
+		return wsChannelNotifications
+	}
+
+	return ""
+}
+
+func wsClose(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, strings.TrimSpace(reason))
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+}
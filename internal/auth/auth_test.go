@@ -109,3 +109,19 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestJWTExpiry(t *testing.T) {
+	token, _ := MakeJWT(uuid.New(), "secret", time.Hour)
+
+	exp, err := JWTExpiry(token, "secret")
+	if err != nil {
+		t.Fatalf("JWTExpiry() error = %v", err)
+	}
+	if until := time.Until(exp); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("JWTExpiry() = %v, want about an hour from now", exp)
+	}
+
+	if _, err := JWTExpiry(token, "wrong_secret"); err == nil {
+		t.Error("JWTExpiry() accepted a token signed with another secret")
+	}
+}
@@ -69,6 +69,26 @@ func ValidateJWT (tokenString, tokenSecret string) (uuid.UUID, error) {
 }
 
 
+//JWTExpiry returns when a valid token stops being accepted
+func JWTExpiry (tokenString, tokenSecret string) (time.Time, error) {
+
+	if _, err := ValidateJWT(tokenString, tokenSecret); err != nil {
+		return time.Time{}, err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return time.Time{}, err
+	}
+
+	if claims.ExpiresAt == nil {
+		return time.Time{}, fmt.Errorf("token has no expiry")
+	}
+
+	return claims.ExpiresAt.Time, nil
+}
+
+
 
 func GetBearerToken (headers http.Header) (string, error) {
 
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries the signature of a webhook request, in the
+// form t=<unix seconds>,v1=<hex hmac>. The HMAC-SHA256 is computed over
+// "<t>.<raw body>". Senders rotating secrets can include several v1 values.
+const WebhookSignatureHeader = "Webhook-Signature"
+
+var ErrMissingSignature = errors.New("missing webhook signature")
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+var ErrSignatureExpired = errors.New("webhook signature outside tolerance window")
+
+// SignWebhook returns the WebhookSignatureHeader value for body sent at
+// timestamp.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%v,v1=%v", t, webhookMAC(secret, t, body))
+}
+
+// VerifyWebhookSignature checks the signature header against each active
+// secret. The timestamp must be within tolerance of now in either direction,
+// so a captured request can't be replayed later.
+func VerifyWebhookSignature(header string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var t string
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	for _, secret := range secrets {
+		expected := []byte(webhookMAC(secret, t, body))
+
+		for _, signature := range signatures {
+			if hmac.Equal(expected, []byte(signature)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// CheckAPIKey compares the request's ApiKey with want in constant time.
+func CheckAPIKey(headers http.Header, want string) error {
+
+	apiKey, err := GetAPIKey(headers)
+
+	if err != nil {
+		return err
+	}
+
+	if want == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(want)) != 1 {
+		return fmt.Errorf("invalid apiKey")
+	}
+
+	return nil
+}
+
+func webhookMAC(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
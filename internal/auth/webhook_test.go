@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"user.upgraded"}`)
+	secrets := []string{"new-secret", "old-secret"}
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		wantErr error
+	}{
+		{
+			name:   "Current secret",
+			header: SignWebhook("new-secret", now, body),
+			body:   body,
+		},
+		{
+			name:   "Secret being rotated out",
+			header: SignWebhook("old-secret", now.Add(-time.Minute), body),
+			body:   body,
+		},
+		{
+			name:   "Several signatures",
+			header: SignWebhook("unknown", now, body) + ",v1=" + webhookMAC("new-secret", "1700000000", body),
+			body:   body,
+		},
+		{
+			name:    "Missing header",
+			header:  "",
+			body:    body,
+			wantErr: ErrMissingSignature,
+		},
+		{
+			name:    "Unknown secret",
+			header:  SignWebhook("unknown", now, body),
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Tampered body",
+			header:  SignWebhook("new-secret", now, body),
+			body:    []byte(`{"event":"user.downgraded"}`),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Too old",
+			header:  SignWebhook("new-secret", now.Add(-6*time.Minute), body),
+			body:    body,
+			wantErr: ErrSignatureExpired,
+		},
+		{
+			name:    "Too far in the future",
+			header:  SignWebhook("new-secret", now.Add(6*time.Minute), body),
+			body:    body,
+			wantErr: ErrSignatureExpired,
+		},
+		{
+			name:    "No timestamp",
+			header:  "v1=" + webhookMAC("new-secret", "", body),
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.header, tt.body, secrets, 5*time.Minute, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWebhookSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "Matching key", header: "ApiKey secret", want: "secret", wantErr: false},
+		{name: "Wrong key", header: "ApiKey secreT", want: "secret", wantErr: true},
+		{name: "No key configured", header: "ApiKey ", want: "", wantErr: true},
+		{name: "Missing header", header: "", want: "secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+
+			err := CheckAPIKey(headers, tt.want)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
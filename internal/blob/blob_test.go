@@ -0,0 +1,83 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put(ctx, "media/abc/full.jpg", strings.NewReader("image")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	r, err := store.Get(ctx, "media/abc/full.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	data, _ := io.ReadAll(r)
+	r.Close()
+
+	if string(data) != "image" {
+		t.Errorf("Get = %q, want image", data)
+	}
+
+	if err := store.Delete(ctx, "media/abc/full.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := store.Get(ctx, "media/abc/full.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Delete(ctx, "media/abc/full.jpg"); err != nil {
+		t.Errorf("deleting a missing blob = %v, want nil", err)
+	}
+}
+
+func TestLocalFailedPut(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Put(ctx, "partial", io.MultiReader(strings.NewReader("half"), errReader{}))
+	if err == nil {
+		t.Fatal("Put succeeded with a failing reader")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("failed Put left %d files behind", len(entries))
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	store := &Local{Dir: t.TempDir()}
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", ".hidden", "a b", `a\b`} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,144 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxHandleLength = 15
+
+// Mention is an @handle in a chirp body. Start and End are offsets in
+// unicode code points, End is exclusive and the span includes the '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// Hashtag is a #tag in a chirp body. Tag is the normalized form used for
+// indexing, Text is the tag as written.
+type Hashtag struct {
+	Tag   string
+	Text  string
+	Start int
+	End   int
+}
+
+type Entities struct {
+	Mentions []Mention
+	Hashtags []Hashtag
+}
+
+// Parse extracts mentions and hashtags from a chirp body. Both must start
+// at the beginning of the body or after a character that can't be part of
+// a word, so emails and URL fragments are not picked up.
+func Parse(body string) Entities {
+	var entities Entities
+
+	runes := []rune(body)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' && runes[i] != '#' {
+			continue
+		}
+
+		if i > 0 && (isWordRune(runes[i-1]) || runes[i-1] == '@' || runes[i-1] == '#') {
+			continue
+		}
+
+		switch runes[i] {
+		case '@':
+			end := i + 1
+			for end < len(runes) && isHandleRune(runes[end]) {
+				end++
+			}
+
+			length := end - i - 1
+			if length == 0 || length > maxHandleLength {
+				i = end - 1
+				continue
+			}
+
+			entities.Mentions = append(entities.Mentions, Mention{
+				Handle: string(runes[i+1 : end]),
+				Start:  i,
+				End:    end,
+			})
+			i = end - 1
+
+		case '#':
+			end := i + 1
+			hasLetter := false
+			for end < len(runes) && isWordRune(runes[end]) {
+				if unicode.IsLetter(runes[end]) {
+					hasLetter = true
+				}
+				end++
+			}
+
+			// #1 or #2024 are numbers, not tags
+			if !hasLetter {
+				i = end - 1
+				continue
+			}
+
+			text := string(runes[i+1 : end])
+			entities.Hashtags = append(entities.Hashtags, Hashtag{
+				Tag:   NormalizeHashtag(text),
+				Text:  text,
+				Start: i,
+				End:   end,
+			})
+			i = end - 1
+		}
+	}
+
+	return entities
+}
+
+// NormalizeHashtag folds a tag to the form it is indexed under, so #Café,
+// #CAFÉ and #café are the same tag. A leading '#' is dropped.
+func NormalizeHashtag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	return strings.ToLower(norm.NFKC.String(tag))
+}
+
+// MentionedHandles returns the distinct, lower cased handles in a chirp.
+func (e Entities) MentionedHandles() []string {
+	return distinct(len(e.Mentions), func(i int) string {
+		return strings.ToLower(e.Mentions[i].Handle)
+	})
+}
+
+// Tags returns the distinct normalized hashtags in a chirp.
+func (e Entities) Tags() []string {
+	return distinct(len(e.Hashtags), func(i int) string {
+		return e.Hashtags[i].Tag
+	})
+}
+
+func distinct(n int, value func(int) string) []string {
+	seen := make(map[string]bool, n)
+	values := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		v := value(i)
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+
+	return values
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
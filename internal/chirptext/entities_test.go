@@ -0,0 +1,88 @@
+package chirptext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		mentions []Mention
+		hashtags []Hashtag
+	}{
+		{
+			name:     "Mention and hashtag",
+			body:     "hi @Chirper, love #Go",
+			mentions: []Mention{{Handle: "Chirper", Start: 3, End: 11}},
+			hashtags: []Hashtag{{Tag: "go", Text: "Go", Start: 18, End: 21}},
+		},
+		{
+			name: "Email is not a mention",
+			body: "mail me at me@example.com",
+		},
+		{
+			name: "Numbers are not hashtags",
+			body: "we're #1 in #2024",
+		},
+		{
+			name:     "Offsets count code points, not bytes",
+			body:     "héllo 🐦 #Café",
+			hashtags: []Hashtag{{Tag: "café", Text: "Café", Start: 8, End: 13}},
+		},
+		{
+			name: "Handle too long",
+			body: "@abcdefghijklmnopqrstuvwxyz",
+		},
+		{
+			name:     "Punctuation ends an entity",
+			body:     "(@bob_1) #tag!",
+			mentions: []Mention{{Handle: "bob_1", Start: 1, End: 7}},
+			hashtags: []Hashtag{{Tag: "tag", Text: "tag", Start: 9, End: 13}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.body)
+			if !reflect.DeepEqual(got.Mentions, tt.mentions) {
+				t.Errorf("Parse() mentions = %+v, want %+v", got.Mentions, tt.mentions)
+			}
+			if !reflect.DeepEqual(got.Hashtags, tt.hashtags) {
+				t.Errorf("Parse() hashtags = %+v, want %+v", got.Hashtags, tt.hashtags)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "#GoLang", want: "golang"},
+		{input: "CAFÉ", want: "café"},
+		{input: "café", want: "café"},
+		{input: "ｆｕｌｌｗｉｄｔｈ", want: "fullwidth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := NormalizeHashtag(tt.input); got != tt.want {
+				t.Errorf("NormalizeHashtag(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	entities := Parse("@Bob @bob #Go #GO #go")
+
+	if got := entities.MentionedHandles(); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("MentionedHandles() = %v", got)
+	}
+	if got := entities.Tags(); !reflect.DeepEqual(got, []string{"go"}) {
+		t.Errorf("Tags() = %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultURLWeight is how many characters a link counts as, whatever its
+// real length, so shortened and long links cost the same.
+const DefaultURLWeight = 23
+
+// Normalize puts a chirp body in the form it is counted and stored in: NFC
+// composed, trimmed, runs of spaces and tabs collapsed to one space and
+// runs of line breaks collapsed to at most one blank line.
+func Normalize(body string) string {
+	body = norm.NFC.String(strings.ReplaceAll(body, "\r\n", "\n"))
+
+	var b strings.Builder
+	b.Grow(len(body))
+
+	space, newlines := false, 0
+	for _, r := range strings.TrimSpace(body) {
+		if r == '\n' {
+			newlines++
+			continue
+		}
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+
+		switch {
+		case newlines > 0:
+			b.WriteString(strings.Repeat("\n", min(newlines, 2)))
+		case space:
+			b.WriteByte(' ')
+		}
+		space, newlines = false, 0
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Length counts a normalized body in grapheme clusters, so 👍🏽 or é
+// written with a combining accent count as one character. Each link counts
+// as urlWeight characters.
+func Length(body string, urlWeight int) int {
+	length := 0
+	last := 0
+
+	for _, link := range URLs(body) {
+		length += uniseg.GraphemeClusterCount(body[last:link.Start]) + urlWeight
+		last = link.End
+	}
+
+	return length + uniseg.GraphemeClusterCount(body[last:])
+}
@@ -0,0 +1,51 @@
+package chirptext
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Trims", input: "  hi  ", want: "hi"},
+		{name: "Collapses spaces and tabs", input: "a \t  b", want: "a b"},
+		{name: "Keeps one blank line", input: "a\r\n\r\n\r\n\nb", want: "a\n\nb"},
+		{name: "Space around line breaks", input: "a  \n  b", want: "a\nb"},
+		{name: "Composes accents", input: "café", want: "café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "ASCII", body: "hello", want: 5},
+		{name: "Accents", body: "héllo wörld", want: 11},
+		{name: "Emoji with skin tone", body: "👍🏽👍🏽", want: 2},
+		{name: "Family emoji", body: "👨‍👩‍👧", want: 1},
+		{name: "Flag", body: "🇩🇰", want: 1},
+		{name: "Link counts as the url weight", body: "read https://example.com/a/very/long/path/that/goes/on", want: 5 + DefaultURLWeight},
+		{name: "Two links", body: "www.a.io and www.b.io", want: 2*DefaultURLWeight + 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.body, DefaultURLWeight); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
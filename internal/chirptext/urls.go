@@ -0,0 +1,57 @@
+package chirptext
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+// URL is a link in a chirp body. Start and End are byte offsets into the
+// body, End is exclusive.
+type URL struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// URLs finds http(s) and www. links in a chirp body. Trailing punctuation
+// is left out, so "see https://example.com." links to https://example.com.
+func URLs(body string) []URL {
+	var urls []URL
+
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		text := strings.TrimRight(body[loc[0]:loc[1]], ".,:;!?'")
+
+		// only drop a closing paren when it isn't part of the link
+		for strings.HasSuffix(text, ")") && strings.Count(text, "(") < strings.Count(text, ")") {
+			text = strings.TrimSuffix(text, ")")
+			text = strings.TrimRight(text, ".,:;!?'")
+		}
+
+		urls = append(urls, URL{
+			Text:  text,
+			Start: loc[0],
+			End:   loc[0] + len(text),
+		})
+	}
+
+	return urls
+}
+
+// Host returns the lower cased host of the link, without a port.
+func (u URL) Host() string {
+	raw := u.Text
+	if strings.HasPrefix(strings.ToLower(raw), "www.") {
+		raw = "http://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(parsed.Hostname())
+}
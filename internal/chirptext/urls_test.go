@@ -0,0 +1,54 @@
+package chirptext
+
+import (
+	"testing"
+)
+
+func TestURLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		want  []string
+		hosts []string
+	}{
+		{
+			name:  "Trailing punctuation",
+			body:  "see https://Example.com/a?b=1.",
+			want:  []string{"https://Example.com/a?b=1"},
+			hosts: []string{"example.com"},
+		},
+		{
+			name:  "www without scheme",
+			body:  "go to www.chirpy.dev:8080/x now",
+			want:  []string{"www.chirpy.dev:8080/x"},
+			hosts: []string{"www.chirpy.dev"},
+		},
+		{
+			name:  "Parens around a link",
+			body:  "(https://en.wikipedia.org/wiki/Go_(programming_language))",
+			want:  []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"},
+			hosts: []string{"en.wikipedia.org"},
+		},
+		{
+			name: "No links",
+			body: "just text, no http here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := URLs(tt.body)
+			if len(got) != len(tt.want) {
+				t.Fatalf("URLs() = %+v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Text != tt.want[i] || tt.body[got[i].Start:got[i].End] != tt.want[i] {
+					t.Errorf("URLs()[%d] = %+v, want %q", i, got[i], tt.want[i])
+				}
+				if host := got[i].Host(); host != tt.hosts[i] {
+					t.Errorf("Host() = %q, want %q", host, tt.hosts[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor is a keyset position in a list ordered by (created_at DESC, id DESC).
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Start returns a cursor positioned before the newest row, used for the first page.
+func Start() Cursor {
+	return Cursor{
+		CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Max,
+	}
+}
+
+// Encode returns the cursor as an opaque url safe string.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Encode. An empty string decodes to Start().
+func Decode(s string) (Cursor, error) {
+	if s == "" {
+		return Start(), nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+
+	if !found {
+		return Cursor{}, fmt.Errorf("invalid cursor: missing separator")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor time: %w", err)
+	}
+
+	parsedID, err := uuid.Parse(id)
+
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return Cursor{CreatedAt: t, ID: parsedID}, nil
+}
+
+// EncodeOffset returns an opaque cursor for lists that can't be keyset
+// paginated, such as search results ordered by relevance.
+func EncodeOffset(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// DecodeOffset parses a cursor produced by EncodeOffset. An empty string decodes to 0.
+func DecodeOffset(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	offset, err := strconv.Atoi(string(raw))
+
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor offset: %q", raw)
+	}
+
+	return offset, nil
+}
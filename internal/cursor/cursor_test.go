@@ -0,0 +1,76 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	want := Cursor{
+		CreatedAt: time.Date(2025, time.March, 4, 10, 30, 0, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	got, err := Decode(want.Encode())
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Cursor
+		wantErr bool
+	}{
+		{
+			name:  "Empty cursor starts at the top",
+			input: "",
+			want:  Start(),
+		},
+		{
+			name:    "Not base64",
+			input:   "not a cursor!",
+			wantErr: true,
+		},
+		{
+			name:    "Missing separator",
+			input:   "bm8tc2VwYXJhdG9y",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (!got.CreatedAt.Equal(tt.want.CreatedAt) || got.ID != tt.want.ID) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOffset(t *testing.T) {
+	got, err := DecodeOffset(EncodeOffset(40))
+	if err != nil || got != 40 {
+		t.Errorf("DecodeOffset(EncodeOffset(40)) = %v, %v", got, err)
+	}
+
+	if _, err := DecodeOffset(EncodeOffset(-1)); err == nil {
+		t.Error("DecodeOffset() accepted a negative offset")
+	}
+
+	if got, err := DecodeOffset(""); err != nil || got != 0 {
+		t.Errorf("DecodeOffset(\"\") = %v, %v", got, err)
+	}
+}
@@ -7,29 +7,47 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body            string
+	UserID          uuid.UUID
+	Kind            string
+	OriginalChirpID uuid.NullUUID
+	InReplyToID     uuid.NullUUID
+	ExpiresAt       sql.NullTime
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.Kind,
+		arg.OriginalChirpID,
+		arg.InReplyToID,
+		arg.ExpiresAt,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,14 +55,21 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.InReplyToID,
+		&i.ExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps
+const deleteChirp = `-- name: DeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = NOW()
 WHERE id = $1
 AND user_id = $2
+AND deleted_at IS NULL
 `
 
 type DeleteChirpParams struct {
@@ -52,13 +77,32 @@ type DeleteChirpParams struct {
 	UserID uuid.UUID
 }
 
-func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, arg.ID, arg.UserID)
-	return err
+// chirps are kept as tombstones until purged, purged once the retention period passes
+func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteChirps = `-- name: DeleteChirps :execrows
+DELETE FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteChirps(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirps, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
- SELECT id, created_at, updated_at, body, user_id FROM chirps
+ SELECT id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at FROM chirps
+ WHERE deleted_at IS NULL
+ AND (expires_at IS NULL OR expires_at > NOW())
 `
 
 func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
@@ -76,6 +120,11 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Kind,
+			&i.OriginalChirpID,
+			&i.InReplyToID,
+			&i.ExpiresAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -91,8 +140,10 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
- SELECT id, created_at, updated_at, body, user_id FROM chirps
+ SELECT id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at FROM chirps
  WHERE chirps.id = $1
+ AND chirps.deleted_at IS NULL
+ AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -104,10 +155,224 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.InReplyToID,
+		&i.ExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const getChirpReplies = `-- name: GetChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at FROM chirps
+WHERE in_reply_to_id = $1::uuid
+AND (expires_at IS NULL OR expires_at > NOW())
+AND (created_at, id) < ($2::timestamp, $3::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $4::int
+`
+
+type GetChirpRepliesParams struct {
+	ChirpID         uuid.UUID
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpReplies(ctx context.Context, arg GetChirpRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies,
+		arg.ChirpID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Kind,
+			&i.OriginalChirpID,
+			&i.InReplyToID,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpWithDeleted = `-- name: GetChirpWithDeleted :one
+SELECT id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at FROM chirps
+WHERE id = $1
+`
+
+// also returns deleted chirps, for tombstones and restoring
+func (q *Queries) GetChirpWithDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpWithDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.InReplyToID,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at FROM chirps
+WHERE id = ANY($1::uuid[])
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW())
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Kind,
+			&i.OriginalChirpID,
+			&i.InReplyToID,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredChirpThreads = `-- name: GetExpiredChirpThreads :many
+WITH RECURSIVE doomed AS (
+    SELECT * FROM (
+        SELECT chirps.id, chirps.user_id, true AS expired FROM chirps
+        WHERE chirps.expires_at <= $1::timestamp
+        ORDER BY chirps.expires_at
+        LIMIT $2::int
+    ) AS expired_chirps
+    UNION
+    SELECT chirps.id, chirps.user_id, false FROM chirps
+    JOIN doomed ON chirps.in_reply_to_id = doomed.id
+)
+SELECT id, user_id, expired FROM doomed
+`
+
+type GetExpiredChirpThreadsParams struct {
+	Now       time.Time
+	BatchSize int32
+}
+
+type GetExpiredChirpThreadsRow struct {
+	ID      uuid.UUID
+	UserID  uuid.UUID
+	Expired bool
+}
+
+// expired chirps due at now, along with every reply below them
+func (q *Queries) GetExpiredChirpThreads(ctx context.Context, arg GetExpiredChirpThreadsParams) ([]GetExpiredChirpThreadsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredChirpThreads, arg.Now, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpiredChirpThreadsRow
+	for rows.Next() {
+		var i GetExpiredChirpThreadsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Expired,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPurgeableChirps = `-- name: GetPurgeableChirps :many
+SELECT id FROM chirps
+WHERE deleted_at < $1::timestamp
+ORDER BY deleted_at
+LIMIT $2::int
+`
+
+type GetPurgeableChirpsParams struct {
+	DeletedBefore time.Time
+	BatchSize     int32
+}
+
+func (q *Queries) GetPurgeableChirps(ctx context.Context, arg GetPurgeableChirpsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getPurgeableChirps, arg.DeletedBefore, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const resetChirps = `-- name: ResetChirps :exec
 DELETE FROM chirps
 `
@@ -116,3 +381,63 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1
+AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.InReplyToID,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $3, updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at, deleted_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Body   string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.UserID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.InReplyToID,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
@@ -6,17 +6,201 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Body            string
+	UserID          uuid.UUID
+	Kind            string
+	OriginalChirpID uuid.NullUUID
+	InReplyToID     uuid.NullUUID
+	ExpiresAt       sql.NullTime
+	DeletedAt       sql.NullTime
+}
+
+type ChirpAttachment struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+}
+
+type ChirpFlag struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Filter     string
+	Reason     string
+	CreatedAt  time.Time
+	ReviewedAt sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Draft struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Content     json.RawMessage
+	Status      string
+	PublishAt   sql.NullTime
+	LockedUntil sql.NullTime
+	Error       string
+	ChirpID     uuid.NullUUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LinkPreview struct {
+	Url         string
+	Status      string
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+	Error       string
+	FetchedAt   time.Time
+}
+
+type List struct {
+	ID          uuid.UUID
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	Private     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type ListMember struct {
+	ListID    uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type MediaFile struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	ContentType   string
+	SizeBytes     int64
+	Width         int32
+	Height        int32
+	BlobKey       string
+	ThumbnailKey  string
+	ThumbnailType string
+	CreatedAt     time.Time
+}
+
+type ModerationAction struct {
 	ID        uuid.UUID
+	ActorID   uuid.UUID
+	Action    string
+	ReportID  uuid.NullUUID
+	UserID    uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	Note      string
 	CreatedAt time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Type          string
+	GroupKey      string
+	ChirpID       uuid.NullUUID
+	LatestActorID uuid.UUID
+	ActorCount    int32
+	ReadAt        sql.NullTime
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	Replies   bool
+	Likes     bool
+	Mentions  bool
+	Follows   bool
 	UpdatedAt time.Time
-	Body      string
+}
+
+type PlanChange struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Event     string
+	FromPlan  string
+	ToPlan    string
+	PeriodEnd sql.NullTime
+	CreatedAt time.Time
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+	CreatedAt time.Time
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
 	UserID    uuid.UUID
+	Position  int32
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +212,125 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID         uuid.UUID
+	ReporterID uuid.NullUUID
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	Category   string
+	Details    string
+	Status     string
+	ClaimedBy  uuid.NullUUID
+	ClaimedAt  sql.NullTime
+	Resolution sql.NullString
+	Note       string
+	ClosedAt   sql.NullTime
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type Subscription struct {
+	UserID             uuid.UUID
+	Plan               string
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+	CanceledAt         sql.NullTime
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type Suspension struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Reason     string
+	ReportID   uuid.NullUUID
+	CreatedBy  uuid.NullUUID
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LiftedAt   sql.NullTime
+	LiftedBy   uuid.NullUUID
+	LiftReason string
+}
+
+type TimelineEntry struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	AuthorID  uuid.UUID
+	CreatedAt time.Time
+}
+
 type User struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	HashedPassword   string
+	IsChirpRed       bool
+	Handle           sql.NullString
+	DisplayName      string
+	Bio              string
+	ProfileUpdatedAt sql.NullTime
+	Role             string
+}
+
+type Warning struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Reason    string
+	ReportID  uuid.NullUUID
+	CreatedBy uuid.NullUUID
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	EndpointID    uuid.UUID
+	EventID       uuid.UUID
+	EventType     string
+	Payload       string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	CompletedAt   sql.NullTime
+}
+
+type WebhookDeliveryAttempt struct {
+	ID           uuid.UUID
+	DeliveryID   uuid.UUID
+	Attempt      int32
+	StatusCode   int32
+	Error        string
+	ResponseBody string
+	DurationMs   int32
+	CreatedAt    time.Time
+}
+
+type WebhookEndpoint struct {
+	ID                  uuid.UUID
+	UserID              uuid.UUID
+	Url                 string
+	Secret              string
+	Events              []string
+	Enabled             bool
+	ConsecutiveFailures int32
+	DisabledAt          sql.NullTime
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type WebhookEvent struct {
 	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpRed     bool
+	Source         string
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         string
+	ResponseStatus int32
+	Error          string
+	Attempts       int32
+	ReceivedAt     time.Time
+	ProcessedAt    sql.NullTime
+	StartedAt      time.Time
 }
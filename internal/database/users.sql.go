@@ -7,30 +7,34 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirp_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirp_red, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
     $2,
-    FALSE
+    FALSE,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -39,6 +43,11 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
@@ -47,7 +56,7 @@ const downgradeChirpRed = `-- name: DowngradeChirpRed :one
 UPDATE users
 SET is_chirp_red = FALSE, updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role
 `
 
 func (q *Queries) DowngradeChirpRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -60,12 +69,17 @@ func (q *Queries) DowngradeChirpRed(ctx context.Context, id uuid.UUID) (User, er
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirp_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role FROM users
 WHERE users.email = $1
 `
 
@@ -79,10 +93,103 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role FROM users
+WHERE LOWER(users.handle) = LOWER($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, lower string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, lower)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role FROM users
+WHERE users.id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role FROM users
+WHERE users.id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpRed,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.ProfileUpdatedAt,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const resetUsers = `-- name: ResetUsers :exec
 DELETE FROM users
 `
@@ -92,6 +199,56 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const setUserRoleByEmail = `-- name: SetUserRoleByEmail :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE email = $1
+`
+
+type SetUserRoleByEmailParams struct {
+	Email string
+	Role  string
+}
+
+func (q *Queries) SetUserRoleByEmail(ctx context.Context, arg SetUserRoleByEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRoleByEmail, arg.Email, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :exec
 UPDATE users
 SET email = $2, hashed_password = $3, updated_at = NOW()
@@ -109,11 +266,49 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	return err
 }
 
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, profile_updated_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
 const upgradeChirpRed = `-- name: UpgradeChirpRed :one
 UPDATE users
 SET is_chirp_red = TRUE, updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirp_red, handle, display_name, bio, profile_updated_at, role
 `
 
 func (q *Queries) UpgradeChirpRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -126,6 +321,11 @@ func (q *Queries) UpgradeChirpRed(ctx context.Context, id uuid.UUID) (User, erro
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.ProfileUpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
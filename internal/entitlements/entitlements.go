@@ -0,0 +1,68 @@
+package entitlements
+
+import (
+	"time"
+)
+
+// Plan is a membership plan. Every user is on exactly one plan.
+type Plan string
+
+const (
+	PlanFree      Plan = "free"
+	PlanChirpyRed Plan = "chirpy_red"
+)
+
+// Entitlements are the capabilities a plan grants. A zero EditWindow means
+// chirps can't be edited.
+type Entitlements struct {
+	Plan            Plan
+	MaxChirpLength  int
+	EditWindow      time.Duration
+	ScheduledChirps bool
+	ChirpsPerHour   int
+	MediaPerChirp   int
+}
+
+// CanEdit reports whether a chirp created at createdAt can still be edited.
+func (e Entitlements) CanEdit(createdAt, now time.Time) bool {
+	return e.EditWindow > 0 && now.Sub(createdAt) <= e.EditWindow
+}
+
+// Catalog maps each plan to its entitlements.
+type Catalog map[Plan]Entitlements
+
+// Default is the catalog Chirpy ships with.
+func Default() Catalog {
+	return Catalog{
+		PlanFree: {
+			Plan:           PlanFree,
+			MaxChirpLength: 140,
+			ChirpsPerHour:  30,
+			MediaPerChirp:  1,
+		},
+		PlanChirpyRed: {
+			Plan:            PlanChirpyRed,
+			MaxChirpLength:  280,
+			EditWindow:      30 * time.Minute,
+			ScheduledChirps: true,
+			ChirpsPerHour:   300,
+			MediaPerChirp:   4,
+		},
+	}
+}
+
+// For returns the entitlements of plan, unknown plans get the free plan.
+func (c Catalog) For(plan Plan) Entitlements {
+	if e, ok := c[plan]; ok {
+		return e
+	}
+	return c[PlanFree]
+}
+
+// PlanOf maps the stored membership flag to a plan.
+func PlanOf(isChirpRed bool) Plan {
+	if isChirpRed {
+		return PlanChirpyRed
+	}
+	return PlanFree
+}
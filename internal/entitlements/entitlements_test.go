@@ -0,0 +1,42 @@
+package entitlements
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFor(t *testing.T) {
+	catalog := Default()
+
+	if got := catalog.For(PlanOf(true)).MaxChirpLength; got != 280 {
+		t.Errorf("chirpy red MaxChirpLength = %d, want 280", got)
+	}
+
+	if got := catalog.For(Plan("platinum")).Plan; got != PlanFree {
+		t.Errorf("unknown plan got %v, want the free plan", got)
+	}
+}
+
+func TestCanEdit(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	catalog := Default()
+
+	tests := []struct {
+		name string
+		plan Plan
+		now  time.Time
+		want bool
+	}{
+		{name: "Free plan", plan: PlanFree, now: created.Add(time.Minute), want: false},
+		{name: "Within window", plan: PlanChirpyRed, now: created.Add(30 * time.Minute), want: true},
+		{name: "After window", plan: PlanChirpyRed, now: created.Add(31 * time.Minute), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := catalog.For(tt.plan).CanEdit(created, tt.now); got != tt.want {
+				t.Errorf("CanEdit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/netguard"
+)
+
+var (
+	// ErrBlockedAddress is returned for links that resolve to loopback,
+	// private, link-local or otherwise internal addresses.
+	ErrBlockedAddress = netguard.ErrBlockedAddress
+	ErrNotHTML        = errors.New("not an html page")
+)
+
+// Fetcher retrieves previews. The zero value is ready to use and refuses to
+// connect to internal addresses.
+type Fetcher struct {
+	// Timeout bounds the whole fetch, redirects included. Defaults to 5s.
+	Timeout time.Duration
+	// MaxBytes is how much of a page is read, metadata past it is ignored.
+	// Defaults to 512KB.
+	MaxBytes int64
+	// MaxRedirects defaults to 5.
+	MaxRedirects int
+	// AllowPrivate lets the fetcher reach internal addresses, only for tests
+	// and local development.
+	AllowPrivate bool
+	UserAgent    string
+
+	once   sync.Once
+	client *http.Client
+}
+
+func (f *Fetcher) timeout() time.Duration {
+	if f.Timeout > 0 {
+		return f.Timeout
+	}
+	return 5 * time.Second
+}
+
+func (f *Fetcher) maxBytes() int64 {
+	if f.MaxBytes > 0 {
+		return f.MaxBytes
+	}
+	return 512 << 10
+}
+
+func (f *Fetcher) maxRedirects() int {
+	if f.MaxRedirects > 0 {
+		return f.MaxRedirects
+	}
+	return 5
+}
+
+// httpClient checks the address of every connection as it is dialed, after
+// DNS resolution, so neither redirects nor DNS rebinding can reach an
+// internal address
+func (f *Fetcher) httpClient() *http.Client {
+	f.once.Do(func() {
+		dialer := netguard.Dialer(f.timeout(), f.AllowPrivate)
+
+		f.client = &http.Client{
+			Timeout: f.timeout(),
+			Transport: &http.Transport{
+				//a proxy would do the dialing for us
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   f.timeout(),
+				ResponseHeaderTimeout: f.timeout(),
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > f.maxRedirects() {
+					return fmt.Errorf("stopped after %d redirects", f.maxRedirects())
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		}
+	})
+
+	return f.client
+}
+
+// Fetch downloads rawURL and parses its metadata.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	target, err := url.Parse(rawURL)
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return Preview{}, fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+
+	res, err := f.httpClient().Do(req)
+
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return Preview{}, ErrBlockedAddress
+		}
+		return Preview{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return Preview{}, fmt.Errorf("page responded %d", res.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, f.maxBytes()))
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	//relative image urls are relative to where the redirects ended
+	return Parse(strings.ToValidUTF8(string(body), ""), res.Request.URL), nil
+}
+
+// Normalize turns a link as written in a chirp into the URL that is fetched
+// and cached: www. links get http://, fragments are dropped.
+func Normalize(link string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(link), "www.") {
+		link = "http://" + link
+	}
+
+	parsed, err := url.Parse(link)
+
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return "", fmt.Errorf("not a web link: %q", link)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+
+	return parsed.String(), nil
+}
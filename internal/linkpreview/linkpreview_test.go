@@ -0,0 +1,150 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const page = `<!doctype html>
+<html><head>
+<title>Fallback &amp; title</title>
+<meta name="description" content="Plain description">
+<meta property="og:title" content="Chirpy &amp; friends">
+<meta content="The   social
+network for birds" property="og:description" />
+<meta property='og:image' content='/img/card.png'>
+<meta name="twitter:title" content="Twitter title">
+<meta property="og:site_name" content="Chirpy">
+</head><body>hello</body></html>`
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://chirpy.example/posts/1")
+
+	got := Parse(page, base)
+	want := Preview{
+		Title:       "Chirpy & friends",
+		Description: "The social network for birds",
+		Image:       "https://chirpy.example/img/card.png",
+		SiteName:    "Chirpy",
+	}
+
+	if got != want {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFallbacks(t *testing.T) {
+	got := Parse(`<html><head><TITLE>Just a title</TITLE><meta name="twitter:description" content="From twitter"><meta property="og:image" content="javascript:alert(1)"></head></html>`, &url.URL{Scheme: "https", Host: "a.example"})
+
+	if got.Title != "Just a title" || got.Description != "From twitter" {
+		t.Errorf("Parse = %+v", got)
+	}
+
+	if got.Image != "" {
+		t.Errorf("Image = %q, want non-http images dropped", got.Image)
+	}
+
+	if !Parse("<p>nothing</p>", nil).Empty() {
+		t.Error("page without metadata isn't empty")
+	}
+
+	long := Parse(`<meta property="og:title" content="`+strings.Repeat("a", 400)+`">`, nil)
+	if n := len([]rune(long.Title)); n != maxTitleLen {
+		t.Errorf("long title has %d runes, want %d", n, maxTitleLen)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, page)
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{}`)
+		case "/missing":
+			http.NotFound(w, r)
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", 10000)+`<meta property="og:title" content="Too far">`)
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, page)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	f := &Fetcher{AllowPrivate: true, MaxBytes: 4096, Timeout: 200 * time.Millisecond}
+
+	got, err := f.Fetch(ctx, server.URL+"/redirect")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	if got.Title != "Chirpy & friends" || got.Image != server.URL+"/img/card.png" {
+		t.Errorf("Fetch = %+v", got)
+	}
+
+	if _, err := f.Fetch(ctx, server.URL+"/json"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("json page = %v, want ErrNotHTML", err)
+	}
+
+	if _, err := f.Fetch(ctx, server.URL+"/missing"); err == nil {
+		t.Error("404 page succeeded")
+	}
+
+	huge, err := f.Fetch(ctx, server.URL+"/huge")
+	if err != nil || huge.Title != "" {
+		t.Errorf("huge page = %+v, %v, want metadata past MaxBytes ignored", huge, err)
+	}
+
+	if _, err := f.Fetch(ctx, server.URL+"/slow"); err == nil {
+		t.Error("slow page succeeded past the timeout")
+	}
+
+	if _, err := f.Fetch(ctx, "ftp://example.com/file"); err == nil {
+		t.Error("ftp link succeeded")
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("blocked fetch reached the server")
+	}))
+	defer server.Close()
+
+	_, err := (&Fetcher{}).Fetch(context.Background(), server.URL)
+
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("loopback fetch = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"www.Example.com/a":             "http://www.example.com/a",
+		"HTTPS://Example.com/a?b=1#top": "https://example.com/a?b=1",
+	}
+
+	for link, want := range tests {
+		if got, err := Normalize(link); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", link, got, err, want)
+		}
+	}
+
+	if _, err := Normalize("mailto:someone@example.com"); err == nil {
+		t.Error("mailto link normalized")
+	}
+}
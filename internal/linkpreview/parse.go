@@ -0,0 +1,113 @@
+// Package linkpreview fetches the OpenGraph and Twitter card metadata of
+// links in chirps.
+package linkpreview
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Preview is what a page says about itself. Image is an absolute http(s)
+// URL or empty.
+type Preview struct {
+	Title       string
+	Description string
+	Image       string
+	SiteName    string
+}
+
+// Empty reports whether the page had nothing worth showing.
+func (p Preview) Empty() bool {
+	return p.Title == "" && p.Description == ""
+}
+
+const (
+	maxTitleLen       = 300
+	maxDescriptionLen = 1000
+	maxSiteNameLen    = 100
+)
+
+var (
+	tagPattern   = regexp.MustCompile(`(?is)<meta\b([^>]*)>|<title\b[^>]*>(.*?)</title>`)
+	attrPattern  = regexp.MustCompile(`(?s)([a-zA-Z_:.-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// Parse reads the metadata of an HTML document fetched from base, which
+// relative image URLs are resolved against. OpenGraph wins over Twitter
+// cards, which win over <title> and the description meta tag.
+func Parse(doc string, base *url.URL) Preview {
+	tags := map[string]string{}
+	title := ""
+
+	for _, match := range tagPattern.FindAllStringSubmatch(doc, -1) {
+		if match[1] == "" {
+			if title == "" {
+				title = match[2]
+			}
+			continue
+		}
+
+		attrs := map[string]string{}
+		for _, attr := range attrPattern.FindAllStringSubmatch(match[1], -1) {
+			attrs[strings.ToLower(attr[1])] = attr[2] + attr[3] + attr[4]
+		}
+
+		//OpenGraph uses property=, Twitter and plain HTML use name=
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+
+		if _, seen := tags[key]; key != "" && !seen {
+			tags[key] = attrs["content"]
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := clean(tags[key]); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := Preview{
+		Title:       truncate(first("og:title", "twitter:title"), maxTitleLen),
+		Description: truncate(first("og:description", "twitter:description", "description"), maxDescriptionLen),
+		SiteName:    truncate(first("og:site_name"), maxSiteNameLen),
+	}
+
+	if preview.Title == "" {
+		preview.Title = truncate(clean(title), maxTitleLen)
+	}
+
+	if image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if ref, err := url.Parse(image); err == nil && base != nil {
+			resolved := base.ResolveReference(ref)
+
+			if resolved.Scheme == "http" || resolved.Scheme == "https" {
+				preview.Image = resolved.String()
+			}
+		}
+	}
+
+	return preview
+}
+
+func clean(s string) string {
+	return strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(s), " "))
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
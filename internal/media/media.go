@@ -0,0 +1,249 @@
+// Package media validates uploaded images and prepares them for serving.
+// Every image is decoded and encoded again, which drops EXIF and any other
+// metadata the original carried.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+)
+
+// Types lists the content types that can be uploaded.
+var Types = []string{TypeJPEG, TypePNG, TypeGIF}
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooManyPixels   = errors.New("image dimensions too large")
+)
+
+// Image is a processed upload.
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+
+	ThumbnailType string
+	Thumbnail     []byte
+}
+
+// Processor turns uploads into Images. The zero value is ready to use.
+type Processor struct {
+	// MaxPixels caps width*height, summed over all frames of a GIF, so a
+	// small file can't decode into gigabytes. Defaults to 25 megapixels.
+	MaxPixels int
+	// ThumbnailSize is the longest side of a thumbnail. Defaults to 320.
+	ThumbnailSize int
+	// JPEGQuality defaults to 85.
+	JPEGQuality int
+}
+
+// Sniff returns the content type of data, ignoring whatever the client
+// claimed it was.
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+
+	for _, t := range Types {
+		if contentType == t {
+			return t, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+}
+
+// Process sniffs, decodes and re-encodes data and renders its thumbnail.
+// JPEGs are rotated upright according to their EXIF orientation first, since
+// the tag doesn't survive.
+func (p Processor) Process(data []byte) (Image, error) {
+	contentType, err := Sniff(data)
+
+	if err != nil {
+		return Image{}, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return Image{}, fmt.Errorf("decode image: %w", err)
+	}
+
+	if config.Width*config.Height > p.maxPixels() {
+		return Image{}, ErrTooManyPixels
+	}
+
+	var out bytes.Buffer
+	var frame image.Image
+
+	switch contentType {
+	case TypeJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+
+		if err != nil {
+			return Image{}, fmt.Errorf("decode image: %w", err)
+		}
+
+		frame = orient(toRGBA(img), jpegOrientation(data))
+		err = jpeg.Encode(&out, frame, &jpeg.Options{Quality: p.jpegQuality()})
+
+		if err != nil {
+			return Image{}, err
+		}
+
+	case TypePNG:
+		img, err := png.Decode(bytes.NewReader(data))
+
+		if err != nil {
+			return Image{}, fmt.Errorf("decode image: %w", err)
+		}
+
+		frame = img
+
+		if err := png.Encode(&out, img); err != nil {
+			return Image{}, err
+		}
+
+	case TypeGIF:
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+
+		if err != nil {
+			return Image{}, fmt.Errorf("decode image: %w", err)
+		}
+
+		pixels := 0
+		for _, f := range g.Image {
+			pixels += f.Bounds().Dx() * f.Bounds().Dy()
+		}
+
+		if pixels > p.maxPixels() {
+			return Image{}, ErrTooManyPixels
+		}
+
+		//the first frame may only cover part of the canvas
+		canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+		draw.Draw(canvas, g.Image[0].Bounds(), g.Image[0], g.Image[0].Bounds().Min, draw.Over)
+		frame = canvas
+
+		if err := gif.EncodeAll(&out, g); err != nil {
+			return Image{}, err
+		}
+	}
+
+	res := Image{
+		ContentType: contentType,
+		Data:        out.Bytes(),
+		Width:       frame.Bounds().Dx(),
+		Height:      frame.Bounds().Dy(),
+	}
+
+	thumb := resize(toRGBA(frame), p.thumbnailSize())
+
+	var thumbOut bytes.Buffer
+
+	//photos stay JPEG, anything that may be transparent becomes PNG
+	if contentType == TypeJPEG {
+		res.ThumbnailType = TypeJPEG
+		err = jpeg.Encode(&thumbOut, thumb, &jpeg.Options{Quality: p.jpegQuality()})
+	} else {
+		res.ThumbnailType = TypePNG
+		err = png.Encode(&thumbOut, thumb)
+	}
+
+	if err != nil {
+		return Image{}, err
+	}
+
+	res.Thumbnail = thumbOut.Bytes()
+
+	return res, nil
+}
+
+func (p Processor) maxPixels() int {
+	if p.MaxPixels > 0 {
+		return p.MaxPixels
+	}
+	return 25_000_000
+}
+
+func (p Processor) thumbnailSize() int {
+	if p.ThumbnailSize > 0 {
+		return p.ThumbnailSize
+	}
+	return 320
+}
+
+func (p Processor) jpegQuality() int {
+	if p.JPEGQuality > 0 {
+		return p.JPEGQuality
+	}
+	return 85
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	return rgba
+}
+
+// resize scales src down to fit a size x size box by averaging the source
+// pixels behind each destination pixel. Smaller images are left alone.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	if w <= size && h <= size {
+		return src
+	}
+
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,189 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is w x h of blue with an 8x8 red square in the top left corner
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{B: 255, A: 255})
+			if x < 8 && y < 8 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			}
+		}
+	}
+
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG encodes img with an EXIF segment carrying orientation
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+
+	//big endian TIFF header, IFD0 at offset 8 with a single orientation entry
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, 0, 0, 0, 0, 0, 0}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(segment) + 2
+
+	app1 := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, segment...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r>>8 > 200 && g>>8 < 80 && b>>8 < 80
+}
+
+func TestSniff(t *testing.T) {
+	if _, err := Sniff([]byte("<html><body>hi</body></html>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Sniff(html) = %v, want ErrUnsupportedType", err)
+	}
+
+	got, err := Sniff(encodePNG(t, testImage(4, 4)))
+	if err != nil || got != TypePNG {
+		t.Errorf("Sniff(png) = %q, %v, want image/png", got, err)
+	}
+}
+
+func TestProcessStripsEXIF(t *testing.T) {
+	data := encodeJPEG(t, testImage(40, 20), 1)
+
+	if jpegOrientation(data) != 1 || !bytes.Contains(data, []byte("Exif")) {
+		t.Fatal("test image has no EXIF segment")
+	}
+
+	img, err := Processor{}.Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	if bytes.Contains(img.Data, []byte("Exif")) {
+		t.Error("processed image still has EXIF")
+	}
+
+	if img.ContentType != TypeJPEG || img.Width != 40 || img.Height != 20 {
+		t.Errorf("got %v %dx%d, want image/jpeg 40x20", img.ContentType, img.Width, img.Height)
+	}
+}
+
+func TestProcessOrientation(t *testing.T) {
+	//stored sideways, displayed after rotating 90 degrees clockwise
+	data := encodeJPEG(t, testImage(40, 20), 6)
+
+	img, err := Processor{}.Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	if img.Width != 20 || img.Height != 40 {
+		t.Fatalf("got %dx%d, want 20x40", img.Width, img.Height)
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the top left corner ends up top right
+	if !isRed(decoded.At(16, 3)) || isRed(decoded.At(3, 3)) {
+		t.Error("image wasn't rotated clockwise")
+	}
+}
+
+func TestProcessThumbnail(t *testing.T) {
+	img, err := Processor{ThumbnailSize: 100}.Process(encodePNG(t, testImage(400, 200)))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("decode thumbnail: %v", err)
+	}
+
+	if b := thumb.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Errorf("thumbnail is %dx%d, want 100x50", b.Dx(), b.Dy())
+	}
+
+	if img.ThumbnailType != TypePNG {
+		t.Errorf("thumbnail type = %v, want image/png", img.ThumbnailType)
+	}
+
+	small, err := Processor{ThumbnailSize: 100}.Process(encodePNG(t, testImage(30, 60)))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	thumb, _ = png.Decode(bytes.NewReader(small.Thumbnail))
+	if b := thumb.Bounds(); b.Dx() != 30 || b.Dy() != 60 {
+		t.Errorf("small image thumbnail is %dx%d, want 30x60", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcessTooManyPixels(t *testing.T) {
+	_, err := Processor{MaxPixels: 100}.Process(encodePNG(t, testImage(20, 20)))
+
+	if !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("Process = %v, want ErrTooManyPixels", err)
+	}
+}
+
+func TestProcessAnimatedGIF(t *testing.T) {
+	palette := color.Palette{color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}}
+
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 10, 10), palette)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Processor{}.Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	out, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out.Image) != 3 {
+		t.Errorf("got %d frames, want 3", len(out.Image))
+	}
+
+	if _, err := (Processor{MaxPixels: 250}).Process(buf.Bytes()); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("3 frames of 100 pixels with MaxPixels 250 = %v, want ErrTooManyPixels", err)
+	}
+}
@@ -0,0 +1,258 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JonMunkholm/server/internal/chirptext"
+	"golang.org/x/text/unicode/norm"
+)
+
+// WordList matches whole words regardless of case or unicode normal form,
+// so "Kerfuffle!" matches "kerfuffle" but "kerfuffles" doesn't.
+type WordList struct {
+	name   string
+	action Action
+	words  map[string]bool
+}
+
+func NewWordList(name string, action Action, words []string) *WordList {
+	folded := make(map[string]bool, len(words))
+
+	for _, word := range words {
+		folded[foldWord(word)] = true
+	}
+
+	return &WordList{
+		name:   name,
+		action: action,
+		words:  folded,
+	}
+}
+
+func (f *WordList) Name() string {
+	return f.name
+}
+
+func (f *WordList) Apply(body string) (string, *Finding) {
+	var b strings.Builder
+	var matches []string
+
+	last := 0
+	start := -1
+
+	flush := func(end int) {
+		word := body[start:end]
+		if !f.words[foldWord(word)] {
+			return
+		}
+		matches = append(matches, word)
+		b.WriteString(body[last:start])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(norm.NFC.String(word))))
+		last = end
+	}
+
+	for i, r := range body {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			flush(i)
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		flush(len(body))
+	}
+
+	if len(matches) == 0 {
+		return body, nil
+	}
+
+	b.WriteString(body[last:])
+
+	return b.String(), &Finding{
+		Filter: f.name,
+		Action: f.action,
+		Reason: fmt.Sprintf("contains %d listed word(s)", len(matches)),
+	}
+}
+
+// Regex matches any of a list of patterns, masking replaces each match.
+type Regex struct {
+	FilterName string
+	Action     Action
+	Patterns   []*regexp.Regexp
+}
+
+func (f *Regex) Name() string {
+	return f.FilterName
+}
+
+func (f *Regex) Apply(body string) (string, *Finding) {
+	var matched []string
+
+	masked := body
+	for _, pattern := range f.Patterns {
+		if !pattern.MatchString(masked) {
+			continue
+		}
+
+		matched = append(matched, pattern.String())
+		masked = pattern.ReplaceAllStringFunc(masked, func(match string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(match))
+		})
+	}
+
+	if len(matched) == 0 {
+		return body, nil
+	}
+
+	return masked, &Finding{
+		Filter: f.FilterName,
+		Action: f.Action,
+		Reason: fmt.Sprintf("matches %v", strings.Join(matched, ", ")),
+	}
+}
+
+// LinkDomains matches links to a blocked domain or any of its subdomains.
+type LinkDomains struct {
+	Action  Action
+	Domains []string
+}
+
+func (f *LinkDomains) Name() string {
+	return "blocked_domains"
+}
+
+func (f *LinkDomains) Apply(body string) (string, *Finding) {
+	var blocked []string
+
+	masked := body
+	// walk backwards so masking doesn't move the offsets of earlier links
+	urls := chirptext.URLs(body)
+	for i := len(urls) - 1; i >= 0; i-- {
+		link := urls[i]
+
+		if !f.blocks(link.Host()) {
+			continue
+		}
+
+		blocked = append(blocked, link.Host())
+		masked = masked[:link.Start] + strings.Repeat("*", utf8.RuneCountInString(link.Text)) + masked[link.End:]
+	}
+
+	if len(blocked) == 0 {
+		return body, nil
+	}
+
+	return masked, &Finding{
+		Filter: f.Name(),
+		Action: f.Action,
+		Reason: fmt.Sprintf("links to %v", strings.Join(blocked, ", ")),
+	}
+}
+
+func (f *LinkDomains) blocks(host string) bool {
+	for _, domain := range f.Domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Spam scores a body from 0 to 1 on shouting, links, repeated characters,
+// repeated words and runs of !!!/???, and acts when the score reaches the
+// threshold. Masking doesn't make sense for spam, so it is treated as flag.
+type Spam struct {
+	Action    Action
+	Threshold float64
+}
+
+func (f *Spam) Name() string {
+	return "spam"
+}
+
+func (f *Spam) Apply(body string) (string, *Finding) {
+	score := SpamScore(body)
+
+	if score < f.Threshold {
+		return body, nil
+	}
+
+	action := f.Action
+	if action == ActionMask {
+		action = ActionFlag
+	}
+
+	return body, &Finding{
+		Filter: f.Name(),
+		Action: action,
+		Reason: fmt.Sprintf("spam score %.2f", score),
+	}
+}
+
+func SpamScore(body string) float64 {
+	letters, upper, punct, longestRun, run := 0, 0, 0, 0, 0
+
+	var prev rune
+	for _, r := range body {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+		if r == '!' || r == '?' {
+			punct++
+		}
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		longestRun = max(longestRun, run)
+		prev = r
+	}
+
+	var shouting float64
+	if letters >= 10 {
+		shouting = float64(upper) / float64(letters)
+	}
+
+	links := min(float64(len(chirptext.URLs(body)))/3, 1)
+
+	// five of the same character in a row starts to look like spam
+	repeated := min(max(float64(longestRun-4), 0)/4, 1)
+
+	var duplicates float64
+	words := strings.Fields(strings.ToLower(body))
+	if len(words) >= 4 {
+		unique := map[string]bool{}
+		for _, word := range words {
+			unique[word] = true
+		}
+		duplicates = 1 - float64(len(unique))/float64(len(words))
+	}
+
+	exclaiming := min(float64(punct)/5, 1)
+
+	return 0.3*shouting + 0.25*links + 0.15*repeated + 0.2*duplicates + 0.1*exclaiming
+}
+
+func foldWord(word string) string {
+	return strings.ToLower(norm.NFKC.String(word))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,109 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Action is what a filter wants done with a chirp. Actions are ordered by
+// severity, the most severe action of any filter wins.
+type Action int
+
+const (
+	ActionAllow Action = iota
+	ActionMask
+	ActionFlag
+	ActionReject
+)
+
+var actionNames = map[Action]string{
+	ActionAllow:  "allow",
+	ActionMask:   "mask",
+	ActionFlag:   "flag",
+	ActionReject: "reject",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+func (a *Action) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	for action, actionName := range actionNames {
+		if actionName == name {
+			*a = action
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown moderation action %q", name)
+}
+
+// Finding records why a filter acted on a chirp.
+type Finding struct {
+	Filter string
+	Action Action
+	Reason string
+}
+
+// Verdict is the outcome of running a chirp through a Chain. Body has any
+// masking applied.
+type Verdict struct {
+	Body     string
+	Action   Action
+	Findings []Finding
+}
+
+// Filter inspects a chirp body. A masking filter returns the masked body,
+// other filters return it unchanged. A nil finding means the body is fine.
+type Filter interface {
+	Name() string
+	Apply(body string) (string, *Finding)
+}
+
+// Chain runs filters in order, each filter sees the body as masked by the
+// filters before it. A rejection stops the chain.
+type Chain struct {
+	Filters []Filter
+}
+
+func (c *Chain) Check(body string) Verdict {
+	verdict := Verdict{
+		Body:     body,
+		Action:   ActionAllow,
+		Findings: []Finding{},
+	}
+
+	if c == nil {
+		return verdict
+	}
+
+	for _, filter := range c.Filters {
+		masked, finding := filter.Apply(verdict.Body)
+
+		if finding == nil {
+			continue
+		}
+
+		verdict.Findings = append(verdict.Findings, *finding)
+
+		if finding.Action == ActionMask {
+			verdict.Body = masked
+		}
+
+		if finding.Action > verdict.Action {
+			verdict.Action = finding.Action
+		}
+
+		if finding.Action == ActionReject {
+			break
+		}
+	}
+
+	return verdict
+}
@@ -0,0 +1,179 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestWordList(t *testing.T) {
+	filter := NewWordList("profanity", ActionMask, []string{"kerfuffle", "Café"})
+
+	tests := []struct {
+		name      string
+		body      string
+		want      string
+		wantMatch bool
+	}{
+		{
+			name:      "Case insensitive with punctuation",
+			body:      "What a Kerfuffle!",
+			want:      "What a *********!",
+			wantMatch: true,
+		},
+		{
+			name: "Whole words only",
+			body: "kerfuffles everywhere",
+			want: "kerfuffles everywhere",
+		},
+		{
+			name:      "Decomposed accents match composed",
+			body:      "meet at the café",
+			want:      "meet at the ****",
+			wantMatch: true,
+		},
+		{
+			name:      "Non latin neighbours",
+			body:      "日本kerfuffle",
+			want:      "日本kerfuffle",
+			wantMatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, finding := filter.Apply(tt.body)
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+			if (finding != nil) != tt.wantMatch {
+				t.Errorf("Apply() finding = %v, wantMatch %v", finding, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := &Chain{
+		Filters: []Filter{
+			NewWordList("profanity", ActionMask, []string{"fornax"}),
+			&Regex{FilterName: "phone", Action: ActionFlag, Patterns: []*regexp.Regexp{regexp.MustCompile(`\d{3}-\d{4}`)}},
+			&LinkDomains{Action: ActionReject, Domains: []string{"spam.example"}},
+			&Spam{Action: ActionFlag, Threshold: 0.5},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		action Action
+		want   string
+	}{
+		{
+			name:   "Clean",
+			body:   "hello world",
+			action: ActionAllow,
+			want:   "hello world",
+		},
+		{
+			name:   "Masked",
+			body:   "fornax!",
+			action: ActionMask,
+			want:   "******!",
+		},
+		{
+			name:   "Flag beats mask",
+			body:   "fornax, call 555-1234",
+			action: ActionFlag,
+			want:   "******, call 555-1234",
+		},
+		{
+			name:   "Subdomain of a blocked domain",
+			body:   "free stuff at https://win.spam.example/now",
+			action: ActionReject,
+		},
+		{
+			name:   "Spammy",
+			body:   "BUY NOW BUY NOW BUY NOW!!!!! https://a.example https://b.example",
+			action: ActionFlag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := chain.Check(tt.body)
+			if verdict.Action != tt.action {
+				t.Errorf("Check() action = %v, want %v (%+v)", verdict.Action, tt.action, verdict.Findings)
+			}
+			if tt.want != "" && verdict.Body != tt.want {
+				t.Errorf("Check() body = %q, want %q", verdict.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	write := func(rules string, mod time.Time) {
+		if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	write(`{"word_lists": [{"name": "a", "action": "reject", "words": ["apple"]}]}`, now.Add(-time.Hour))
+
+	chain, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	pipeline := NewPipeline(chain)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pipeline.Watch(ctx, path, 5*time.Millisecond)
+
+	if got := pipeline.Check("apple").Action; got != ActionReject {
+		t.Fatalf("Check() = %v before reload, want reject", got)
+	}
+
+	// a broken file keeps the old rules
+	write(`{"regex": [{"name": "b", "action": "flag", "patterns": ["("]}]}`, now.Add(-time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if got := pipeline.Check("apple").Action; got != ActionReject {
+		t.Fatalf("Check() = %v after a broken reload, want reject", got)
+	}
+
+	write(`{"word_lists": [{"name": "a", "action": "flag", "words": ["banana"]}]}`, now)
+
+	deadline := time.Now().Add(time.Second)
+	for pipeline.Check("banana").Action != ActionFlag {
+		if time.Now().After(deadline) {
+			t.Fatal("rules were not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got := pipeline.Check("apple").Action; got != ActionAllow {
+		t.Errorf("Check() = %v after reload, want allow", got)
+	}
+}
+
+func TestUnknownAction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"spam": {"action": "explode"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() accepted an unknown action")
+	}
+}
@@ -0,0 +1,171 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+// Rules is the JSON rules file format. Filters run in the order word
+// lists, regex lists, blocked domains, spam.
+//
+//	{
+//	  "word_lists": [{"name": "profanity", "action": "mask", "words": ["kerfuffle"]}],
+//	  "regex": [{"name": "phone_numbers", "action": "flag", "patterns": ["\\d{3}-\\d{4}"]}],
+//	  "blocked_domains": {"action": "reject", "domains": ["spam.example"]},
+//	  "spam": {"action": "flag", "threshold": 0.6}
+//	}
+type Rules struct {
+	WordLists []struct {
+		Name   string   `json:"name"`
+		Action Action   `json:"action"`
+		Words  []string `json:"words"`
+	} `json:"word_lists"`
+	Regex []struct {
+		Name     string   `json:"name"`
+		Action   Action   `json:"action"`
+		Patterns []string `json:"patterns"`
+	} `json:"regex"`
+	BlockedDomains *struct {
+		Action  Action   `json:"action"`
+		Domains []string `json:"domains"`
+	} `json:"blocked_domains"`
+	Spam *struct {
+		Action    Action  `json:"action"`
+		Threshold float64 `json:"threshold"`
+	} `json:"spam"`
+}
+
+// DefaultChain masks the words Chirpy has always considered profane. It is
+// used when no rules file is configured.
+func DefaultChain() *Chain {
+	return &Chain{
+		Filters: []Filter{
+			NewWordList("profanity", ActionMask, []string{"kerfuffle", "sharbert", "fornax"}),
+		},
+	}
+}
+
+// Compile builds a Chain from rules, failing on invalid regex patterns.
+func (rules Rules) Compile() (*Chain, error) {
+	chain := &Chain{}
+
+	for _, list := range rules.WordLists {
+		chain.Filters = append(chain.Filters, NewWordList(list.Name, list.Action, list.Words))
+	}
+
+	for _, list := range rules.Regex {
+		filter := &Regex{FilterName: list.Name, Action: list.Action}
+
+		for _, pattern := range list.Patterns {
+			compiled, err := regexp.Compile(pattern)
+
+			if err != nil {
+				return nil, fmt.Errorf("regex list %v: %w", list.Name, err)
+			}
+
+			filter.Patterns = append(filter.Patterns, compiled)
+		}
+
+		chain.Filters = append(chain.Filters, filter)
+	}
+
+	if rules.BlockedDomains != nil {
+		chain.Filters = append(chain.Filters, &LinkDomains{
+			Action:  rules.BlockedDomains.Action,
+			Domains: rules.BlockedDomains.Domains,
+		})
+	}
+
+	if rules.Spam != nil {
+		chain.Filters = append(chain.Filters, &Spam{
+			Action:    rules.Spam.Action,
+			Threshold: rules.Spam.Threshold,
+		})
+	}
+
+	return chain, nil
+}
+
+// LoadFile reads and compiles a JSON rules file.
+func LoadFile(path string) (*Chain, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse %v: %w", path, err)
+	}
+
+	return rules.Compile()
+}
+
+// Pipeline holds the current Chain and can swap it for a reloaded one
+// while chirps are being checked.
+type Pipeline struct {
+	chain atomic.Pointer[Chain]
+}
+
+func NewPipeline(chain *Chain) *Pipeline {
+	p := &Pipeline{}
+	p.chain.Store(chain)
+	return p
+}
+
+func (p *Pipeline) Check(body string) Verdict {
+	return p.chain.Load().Check(body)
+}
+
+// Watch reloads the rules file whenever its modification time changes,
+// checking every interval until ctx is done. A file that fails to load is
+// logged and the previous rules stay in place.
+func (p *Pipeline) Watch(ctx context.Context, path string, interval time.Duration) {
+	var lastMod time.Time
+
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			log.Printf("moderation: can't stat rules file: %v", err)
+			continue
+		}
+
+		if info.ModTime().Equal(lastMod) {
+			continue
+		}
+
+		lastMod = info.ModTime()
+
+		chain, err := LoadFile(path)
+
+		if err != nil {
+			log.Printf("moderation: keeping previous rules, reload failed: %v", err)
+			continue
+		}
+
+		p.chain.Store(chain)
+		log.Printf("moderation: reloaded rules from %v", path)
+	}
+}
@@ -0,0 +1,71 @@
+// Package netguard keeps requests the server makes on behalf of users, like
+// link previews and outbound webhooks, off loopback, private and other
+// internal addresses.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for connections to loopback, private,
+// link-local or otherwise internal addresses.
+var ErrBlockedAddress = errors.New("address not allowed")
+
+// blockedPrefixes are ranges that aren't the public internet, on top of what
+// netip already classifies as private, loopback and link-local
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Blocked reports whether ip is an address user-supplied URLs must not reach.
+func Blocked(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Control is a net.Dialer Control func refusing blocked addresses. It runs
+// for every connection after DNS resolution, so neither redirects nor DNS
+// rebinding get past it.
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+
+	if err != nil || Blocked(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+
+	return nil
+}
+
+// Dialer returns a dialer that refuses blocked addresses, unless
+// allowPrivate is set for tests and local development.
+func Dialer(timeout time.Duration, allowPrivate bool) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	if !allowPrivate {
+		dialer.Control = Control
+	}
+
+	return dialer
+}
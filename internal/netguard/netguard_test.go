@@ -0,0 +1,49 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	blocked := []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fc00::1", "::ffff:127.0.0.1", "224.0.0.1"}
+	allowed := []string{"93.184.216.34", "1.1.1.1", "2606:4700:4700::1111"}
+
+	for _, raw := range blocked {
+		if !Blocked(netip.MustParseAddr(raw)) {
+			t.Errorf("%v is allowed", raw)
+		}
+	}
+
+	for _, raw := range allowed {
+		if Blocked(netip.MustParseAddr(raw)) {
+			t.Errorf("%v is blocked", raw)
+		}
+	}
+}
+
+func TestDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	_, err = Dialer(time.Second, false).DialContext(context.Background(), "tcp", listener.Addr().String())
+
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("loopback dial = %v, want ErrBlockedAddress", err)
+	}
+
+	conn, err := Dialer(time.Second, true).DialContext(context.Background(), "tcp", listener.Addr().String())
+
+	if err != nil {
+		t.Fatalf("loopback dial with allowPrivate = %v", err)
+	}
+	conn.Close()
+}
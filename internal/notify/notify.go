@@ -0,0 +1,93 @@
+// Package notify decides which notifications a user gets and how they are
+// grouped in their inbox.
+package notify
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Type is what happened to the recipient.
+type Type string
+
+const (
+	TypeReply   Type = "reply"
+	TypeLike    Type = "like"
+	TypeMention Type = "mention"
+	TypeFollow  Type = "follow"
+)
+
+// Types lists every notification type.
+var Types = []Type{TypeReply, TypeLike, TypeMention, TypeFollow}
+
+// GroupKey returns the key unread notifications are merged on. Likes and
+// replies group per chirp, follows all group together and mentions group per
+// mentioning chirp, which in practice means they aren't grouped.
+func GroupKey(t Type, chirpID uuid.NullUUID) string {
+	if t == TypeFollow || !chirpID.Valid {
+		return string(t)
+	}
+	return string(t) + ":" + chirpID.UUID.String()
+}
+
+// Preferences are the notification types a user wants. The zero value turns
+// everything off, use Defaults for a user that never changed anything.
+type Preferences struct {
+	Replies  bool
+	Likes    bool
+	Mentions bool
+	Follows  bool
+}
+
+// Defaults turns every notification type on.
+func Defaults() Preferences {
+	return Preferences{Replies: true, Likes: true, Mentions: true, Follows: true}
+}
+
+// Allows reports whether notifications of type t are wanted.
+func (p Preferences) Allows(t Type) bool {
+	switch t {
+	case TypeReply:
+		return p.Replies
+	case TypeLike:
+		return p.Likes
+	case TypeMention:
+		return p.Mentions
+	case TypeFollow:
+		return p.Follows
+	}
+	return false
+}
+
+// Summary renders a grouped notification, such as "@amy and 4 others liked
+// your chirp". actor is the latest actor's display name and may be empty.
+func Summary(t Type, actor string, count int) string {
+	var action string
+
+	switch t {
+	case TypeReply:
+		action = "replied to your chirp"
+	case TypeLike:
+		action = "liked your chirp"
+	case TypeMention:
+		action = "mentioned you"
+	case TypeFollow:
+		action = "followed you"
+	default:
+		action = "did something"
+	}
+
+	switch {
+	case count <= 1 && actor == "":
+		return "Someone " + action
+	case count <= 1:
+		return actor + " " + action
+	case actor == "":
+		return fmt.Sprintf("%d people %s", count, action)
+	case count == 2:
+		return actor + " and 1 other " + action
+	}
+
+	return fmt.Sprintf("%s and %d others %s", actor, count-1, action)
+}
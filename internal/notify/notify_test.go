@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupKey(t *testing.T) {
+	chirp := uuid.NullUUID{UUID: uuid.MustParse("6f1c1f9e-2d0b-4b8e-9a57-3f4c2b1d0e11"), Valid: true}
+	other := uuid.NullUUID{UUID: uuid.MustParse("0b7d6f2a-5c1e-4f3d-8e2a-9c8b7a6d5e4f"), Valid: true}
+
+	if GroupKey(TypeLike, chirp) != GroupKey(TypeLike, chirp) {
+		t.Error("likes on the same chirp aren't grouped")
+	}
+
+	if GroupKey(TypeLike, chirp) == GroupKey(TypeLike, other) {
+		t.Error("likes on different chirps are grouped")
+	}
+
+	if GroupKey(TypeLike, chirp) == GroupKey(TypeReply, chirp) {
+		t.Error("likes and replies are grouped")
+	}
+
+	if GroupKey(TypeFollow, uuid.NullUUID{}) != "follow" {
+		t.Errorf("follow key = %q, want follow", GroupKey(TypeFollow, uuid.NullUUID{}))
+	}
+}
+
+func TestAllows(t *testing.T) {
+	for _, typ := range Types {
+		if !Defaults().Allows(typ) {
+			t.Errorf("defaults don't allow %v", typ)
+		}
+	}
+
+	prefs := Defaults()
+	prefs.Likes = false
+
+	if prefs.Allows(TypeLike) {
+		t.Error("likes are allowed after turning them off")
+	}
+
+	if !prefs.Allows(TypeReply) {
+		t.Error("turning off likes turned off replies")
+	}
+
+	if prefs.Allows(Type("poke")) {
+		t.Error("unknown type is allowed")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		typ   Type
+		actor string
+		count int
+		want  string
+	}{
+		{TypeLike, "@amy", 1, "@amy liked your chirp"},
+		{TypeLike, "@amy", 2, "@amy and 1 other liked your chirp"},
+		{TypeLike, "@amy", 5, "@amy and 4 others liked your chirp"},
+		{TypeLike, "", 5, "5 people liked your chirp"},
+		{TypeFollow, "", 1, "Someone followed you"},
+		{TypeMention, "@bob", 1, "@bob mentioned you"},
+		{TypeReply, "@bob", 3, "@bob and 2 others replied to your chirp"},
+	}
+
+	for _, tt := range tests {
+		if got := Summary(tt.typ, tt.actor, tt.count); got != tt.want {
+			t.Errorf("Summary(%v, %q, %d) = %q, want %q", tt.typ, tt.actor, tt.count, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package poll validates polls attached to chirps and summarizes their
+// results.
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MinOptions = 2
+	MaxOptions = 4
+	// MaxOptionLength is counted in user-perceived characters.
+	MaxOptionLength = 25
+	MinDuration     = 5 * time.Minute
+	MaxDuration     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrOptionCount     = fmt.Errorf("a poll needs %d to %d options", MinOptions, MaxOptions)
+	ErrEmptyOption     = errors.New("poll options can't be empty")
+	ErrOptionLength    = fmt.Errorf("poll options are limited to %d characters", MaxOptionLength)
+	ErrDuplicateOption = errors.New("poll options must be different")
+	ErrClosingTime     = errors.New("a poll must close between 5 minutes and 7 days from now")
+)
+
+// NormalizeOption puts an option in the form it is compared and stored in:
+// NFC composed, with all whitespace collapsed to single spaces.
+func NormalizeOption(option string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(option)), " ")
+}
+
+// Validate checks the options and closing time of a new poll, created at
+// now, and returns the normalized options. Options differing only in case
+// count as duplicates.
+func Validate(options []string, closesAt, now time.Time) ([]string, error) {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return nil, ErrOptionCount
+	}
+
+	normalized := make([]string, 0, len(options))
+	seen := map[string]bool{}
+
+	for _, option := range options {
+		option = NormalizeOption(option)
+
+		if option == "" {
+			return nil, ErrEmptyOption
+		}
+
+		if uniseg.GraphemeClusterCount(option) > MaxOptionLength {
+			return nil, ErrOptionLength
+		}
+
+		key := strings.ToLower(option)
+		if seen[key] {
+			return nil, ErrDuplicateOption
+		}
+		seen[key] = true
+
+		normalized = append(normalized, option)
+	}
+
+	if open := closesAt.Sub(now); open < MinDuration || open > MaxDuration {
+		return nil, ErrClosingTime
+	}
+
+	return normalized, nil
+}
+
+// Results are the tallies of a poll, Votes is indexed like the options.
+type Results struct {
+	Votes    []int64
+	ClosesAt time.Time
+}
+
+// Closed reports whether voting has ended at now.
+func (r Results) Closed(now time.Time) bool {
+	return !now.Before(r.ClosesAt)
+}
+
+// Total is the number of votes cast.
+func (r Results) Total() int64 {
+	var total int64
+	for _, votes := range r.Votes {
+		total += votes
+	}
+	return total
+}
+
+// Percentages are each option's share of the votes, rounded to whole
+// percents with the largest remainder method so they add up to 100. They
+// are all 0 before the first vote.
+func (r Results) Percentages() []int {
+	shares := make([]int, len(r.Votes))
+	total := r.Total()
+
+	if total == 0 {
+		return shares
+	}
+
+	remainders := make([]int64, len(r.Votes))
+	left := 100
+
+	for i, votes := range r.Votes {
+		shares[i] = int(votes * 100 / total)
+		remainders[i] = votes * 100 % total
+		left -= shares[i]
+	}
+
+	//the points lost to rounding go to the largest remainders, earlier
+	//options first on ties
+	for ; left > 0; left-- {
+		best := 0
+		for i := range remainders {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		shares[best]++
+		remainders[best] = -1
+	}
+
+	return shares
+}
+
+// Winners are the positions of the options with the most votes, several on
+// a tie and none before the first vote.
+func (r Results) Winners() []int {
+	var winners []int
+	var most int64
+
+	for i, votes := range r.Votes {
+		switch {
+		case votes == 0:
+		case votes > most:
+			most = votes
+			winners = []int{i}
+		case votes == most:
+			winners = append(winners, i)
+		}
+	}
+
+	return winners
+}
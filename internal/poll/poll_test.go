@@ -0,0 +1,86 @@
+package poll
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	day := now.Add(24 * time.Hour)
+
+	got, err := Validate([]string{"  Cats ", "Dogs\tand\nbirds"}, day, now)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	if want := []string{"Cats", "Dogs and birds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		options  []string
+		closesAt time.Time
+		want     error
+	}{
+		{"one option", []string{"yes"}, day, ErrOptionCount},
+		{"five options", []string{"a", "b", "c", "d", "e"}, day, ErrOptionCount},
+		{"blank option", []string{"yes", "   "}, day, ErrEmptyOption},
+		{"long option", []string{"yes", strings.Repeat("n", MaxOptionLength+1)}, day, ErrOptionLength},
+		{"duplicate", []string{"Yes", "yes "}, day, ErrDuplicateOption},
+		{"closes too soon", []string{"yes", "no"}, now.Add(time.Minute), ErrClosingTime},
+		{"closes too late", []string{"yes", "no"}, now.Add(8 * 24 * time.Hour), ErrClosingTime},
+		{"already closed", []string{"yes", "no"}, now.Add(-time.Hour), ErrClosingTime},
+	}
+
+	for _, tt := range tests {
+		if _, err := Validate(tt.options, tt.closesAt, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	//emoji made of several code points count once
+	family := strings.Repeat("👨‍👩‍👧", MaxOptionLength)
+	if _, err := Validate([]string{family, "no"}, day, now); err != nil {
+		t.Errorf("emoji option: %v", err)
+	}
+}
+
+func TestResults(t *testing.T) {
+	closesAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	r := Results{Votes: []int64{1, 1, 1}, ClosesAt: closesAt}
+
+	if r.Closed(closesAt.Add(-time.Second)) || !r.Closed(closesAt) {
+		t.Error("poll should close exactly at ClosesAt")
+	}
+
+	if got := r.Total(); got != 3 {
+		t.Errorf("Total = %d, want 3", got)
+	}
+
+	if got, want := r.Percentages(), []int{34, 33, 33}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Percentages = %v, want %v", got, want)
+	}
+
+	if got, want := r.Winners(), []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Winners = %v, want %v", got, want)
+	}
+
+	r.Votes = []int64{2, 5, 0, 1}
+	if got, want := r.Percentages(), []int{25, 63, 0, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Percentages = %v, want %v", got, want)
+	}
+
+	if got, want := r.Winners(), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Winners = %v, want %v", got, want)
+	}
+
+	empty := Results{Votes: []int64{0, 0}}
+	if got := empty.Percentages(); !reflect.DeepEqual(got, []int{0, 0}) || empty.Winners() != nil {
+		t.Errorf("no votes: Percentages = %v, Winners = %v", got, empty.Winners())
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts events per key in fixed windows. Limits are passed on each
+// call so keys on different plans can share one Limiter.
+type Limiter struct {
+	Window time.Duration
+	// Now defaults to time.Now
+	Now func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+func New(w time.Duration) *Limiter {
+	return &Limiter{Window: w}
+}
+
+// Allow records an event for key and reports whether it is within limit.
+// When it isn't, retryAfter is how long until the window resets.
+func (l *Limiter) Allow(key string, limit int) (ok bool, retryAfter time.Duration) {
+	now := time.Now()
+	if l.Now != nil {
+		now = l.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.windows == nil {
+		l.windows = map[string]*window{}
+	}
+
+	w, found := l.windows[key]
+
+	if !found || now.Sub(w.start) >= l.Window {
+		if now.Sub(l.lastSweep) >= l.Window {
+			l.sweep(now)
+		}
+
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= limit {
+		return false, w.start.Add(l.Window).Sub(now)
+	}
+
+	w.count++
+
+	return true, 0
+}
+
+// Refund gives back an event Allow recorded for key, for events that ended
+// up not happening. Events from a window that has since reset aren't
+// refunded.
+func (l *Limiter) Refund(key string) {
+	now := time.Now()
+	if l.Now != nil {
+		now = l.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, found := l.windows[key]
+
+	if !found || now.Sub(w.start) >= l.Window || w.count == 0 {
+		return
+	}
+
+	w.count--
+}
+
+// sweep drops expired windows so idle keys don't pile up. Allow runs it at
+// most once per Window, walking the map is O(keys).
+func (l *Limiter) sweep(now time.Time) {
+	l.lastSweep = now
+
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.Window {
+			delete(l.windows, key)
+		}
+	}
+}
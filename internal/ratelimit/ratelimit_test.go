@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := New(time.Hour)
+	limiter.Now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a", 3); !ok {
+			t.Fatalf("event %d was limited", i+1)
+		}
+	}
+
+	now = now.Add(20 * time.Minute)
+
+	ok, retryAfter := limiter.Allow("a", 3)
+	if ok {
+		t.Fatal("4th event was allowed")
+	}
+	if retryAfter != 40*time.Minute {
+		t.Errorf("retryAfter = %v, want 40m", retryAfter)
+	}
+
+	if ok, _ := limiter.Allow("b", 3); !ok {
+		t.Error("other key was limited")
+	}
+
+	if ok, _ := limiter.Allow("a", 5); !ok {
+		t.Error("higher limit was limited")
+	}
+
+	now = now.Add(40 * time.Minute)
+
+	if ok, _ := limiter.Allow("a", 3); !ok {
+		t.Error("event after the window was limited")
+	}
+}
+
+func TestRefund(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := New(time.Hour)
+	limiter.Now = func() time.Time { return now }
+
+	limiter.Allow("a", 2)
+	limiter.Allow("a", 2)
+	limiter.Refund("a")
+
+	if ok, _ := limiter.Allow("a", 2); !ok {
+		t.Fatal("refunded event still counted")
+	}
+
+	if ok, _ := limiter.Allow("a", 2); ok {
+		t.Fatal("3rd event was allowed")
+	}
+
+	// nothing to refund for unknown keys or past the window
+	limiter.Refund("b")
+	now = now.Add(time.Hour)
+	limiter.Refund("a")
+
+	if ok, _ := limiter.Allow("b", 1); !ok {
+		t.Error("refund of an unknown key went negative")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := New(time.Minute)
+	limiter.Now = func() time.Time { return now }
+
+	limiter.Allow("a", 1)
+	now = now.Add(30 * time.Second)
+	limiter.Allow("b", 1)
+
+	now = now.Add(30 * time.Second)
+	limiter.Allow("c", 1)
+
+	if len(limiter.windows) != 2 {
+		t.Errorf("%d windows kept, want 2", len(limiter.windows))
+	}
+
+	// b has expired, but the last sweep was less than a window ago
+	now = now.Add(40 * time.Second)
+	limiter.Allow("d", 1)
+
+	if len(limiter.windows) != 3 {
+		t.Errorf("%d windows kept, want 3", len(limiter.windows))
+	}
+}
@@ -0,0 +1,165 @@
+// Package report defines what users can report and how a report moves
+// through the moderation queue.
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// MaxDetailsLength is the most a reporter can write, in characters.
+const MaxDetailsLength = 1000
+
+// Category is what a chirp or account is reported for.
+type Category string
+
+const (
+	CategorySpam          Category = "spam"
+	CategoryHarassment    Category = "harassment"
+	CategoryHate          Category = "hate"
+	CategoryViolence      Category = "violence"
+	CategorySelfHarm      Category = "self_harm"
+	CategorySexual        Category = "sexual"
+	CategoryImpersonation Category = "impersonation"
+	CategoryOther         Category = "other"
+)
+
+// Categories lists every category.
+var Categories = []Category{
+	CategorySpam,
+	CategoryHarassment,
+	CategoryHate,
+	CategoryViolence,
+	CategorySelfHarm,
+	CategorySexual,
+	CategoryImpersonation,
+	CategoryOther,
+}
+
+// Status is where a report is in the queue. Open reports wait for a
+// moderator to claim them, the claiming moderator then resolves or dismisses
+// them, which closes them.
+type Status string
+
+const (
+	StatusOpen      Status = "open"
+	StatusClaimed   Status = "claimed"
+	StatusResolved  Status = "resolved"
+	StatusDismissed Status = "dismissed"
+)
+
+// Statuses lists every status.
+var Statuses = []Status{StatusOpen, StatusClaimed, StatusResolved, StatusDismissed}
+
+// Closed reports whether nothing more can happen to a report.
+func (s Status) Closed() bool {
+	return s == StatusResolved || s == StatusDismissed
+}
+
+// Resolution is what a moderator did about a report they upheld.
+type Resolution string
+
+const (
+	ResolutionDeleteChirp Resolution = "delete_chirp"
+	ResolutionSuspendUser Resolution = "suspend_user"
+	ResolutionWarnUser    Resolution = "warn_user"
+)
+
+// Resolutions lists every resolution.
+var Resolutions = []Resolution{ResolutionDeleteChirp, ResolutionSuspendUser, ResolutionWarnUser}
+
+var (
+	ErrDetailsRequired = errors.New("details are required for reports in the other category")
+	ErrDetailsLength   = fmt.Errorf("details are limited to %d characters", MaxDetailsLength)
+	ErrClosed          = errors.New("report is already closed")
+	ErrClaimed         = errors.New("report is claimed by another moderator")
+	ErrNotClaimed      = errors.New("report must be claimed before it is closed")
+)
+
+// ParseCategory returns the category named s.
+func ParseCategory(s string) (Category, error) {
+	for _, category := range Categories {
+		if string(category) == s {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("unknown category %q", s)
+}
+
+// ParseStatus returns the status named s.
+func ParseStatus(s string) (Status, error) {
+	for _, status := range Statuses {
+		if string(status) == s {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown status %q", s)
+}
+
+// ParseResolution returns the resolution named s.
+func ParseResolution(s string) (Resolution, error) {
+	for _, resolution := range Resolutions {
+		if string(resolution) == s {
+			return resolution, nil
+		}
+	}
+	return "", fmt.Errorf("unknown resolution %q", s)
+}
+
+// Validate checks a new report and returns its category and trimmed details.
+// Reports in CategoryOther must say what is wrong.
+func Validate(category, details string) (Category, string, error) {
+	parsed, err := ParseCategory(category)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	details = strings.TrimSpace(details)
+
+	if utf8.RuneCountInString(details) > MaxDetailsLength {
+		return "", "", ErrDetailsLength
+	}
+
+	if parsed == CategoryOther && details == "" {
+		return "", "", ErrDetailsRequired
+	}
+
+	return parsed, details, nil
+}
+
+// CheckClaim returns why moderator can't claim a report, or nil. Claiming a
+// report again is allowed so retries are harmless.
+func CheckClaim(status Status, claimedBy uuid.NullUUID, moderator uuid.UUID) error {
+	if status.Closed() {
+		return ErrClosed
+	}
+
+	if status == StatusClaimed && claimedBy.Valid && claimedBy.UUID != moderator {
+		return ErrClaimed
+	}
+
+	return nil
+}
+
+// CheckClose returns why moderator can't resolve or dismiss a report, or
+// nil. Only the moderator holding the claim can close a report.
+func CheckClose(status Status, claimedBy uuid.NullUUID, moderator uuid.UUID) error {
+	if status.Closed() {
+		return ErrClosed
+	}
+
+	if status != StatusClaimed || !claimedBy.Valid {
+		return ErrNotClaimed
+	}
+
+	if claimedBy.UUID != moderator {
+		return ErrClaimed
+	}
+
+	return nil
+}
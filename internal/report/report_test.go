@@ -0,0 +1,101 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		details  string
+		want     Category
+		wantErr  bool
+	}{
+		{"spam without details", "spam", "", CategorySpam, false},
+		{"details trimmed", "harassment", "  keeps replying  ", CategoryHarassment, false},
+		{"unknown category", "rude", "", "", true},
+		{"empty category", "", "details", "", true},
+		{"other needs details", "other", "   ", "", true},
+		{"other with details", "other", "copies my art", CategoryOther, false},
+		{"details too long", "spam", strings.Repeat("a", MaxDetailsLength+1), "", true},
+		{"details at the limit", "spam", strings.Repeat("é", MaxDetailsLength), CategorySpam, false},
+	}
+
+	for _, tt := range tests {
+		category, details, err := Validate(tt.category, tt.details)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		if category != tt.want {
+			t.Errorf("%s: category = %q, want %q", tt.name, category, tt.want)
+		}
+
+		if err == nil && details != strings.TrimSpace(tt.details) {
+			t.Errorf("%s: details = %q", tt.name, details)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, category := range Categories {
+		if got, err := ParseCategory(string(category)); err != nil || got != category {
+			t.Errorf("ParseCategory(%q) = %q, %v", category, got, err)
+		}
+	}
+
+	for _, status := range Statuses {
+		if got, err := ParseStatus(string(status)); err != nil || got != status {
+			t.Errorf("ParseStatus(%q) = %q, %v", status, got, err)
+		}
+	}
+
+	for _, resolution := range Resolutions {
+		if got, err := ParseResolution(string(resolution)); err != nil || got != resolution {
+			t.Errorf("ParseResolution(%q) = %q, %v", resolution, got, err)
+		}
+	}
+
+	if _, err := ParseResolution("ban_forever"); err == nil {
+		t.Error("unknown resolution parsed")
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	me := uuid.MustParse("6f1c1f9e-2d0b-4b8e-9a57-3f4c2b1d0e11")
+	other := uuid.MustParse("0b7d6f2a-5c1e-4f3d-8e2a-9c8b7a6d5e4f")
+
+	mine := uuid.NullUUID{UUID: me, Valid: true}
+	theirs := uuid.NullUUID{UUID: other, Valid: true}
+
+	tests := []struct {
+		name      string
+		status    Status
+		claimedBy uuid.NullUUID
+		claimErr  error
+		closeErr  error
+	}{
+		{"open", StatusOpen, uuid.NullUUID{}, nil, ErrNotClaimed},
+		{"claimed by me", StatusClaimed, mine, nil, nil},
+		{"claimed by someone else", StatusClaimed, theirs, ErrClaimed, ErrClaimed},
+		{"resolved", StatusResolved, mine, ErrClosed, ErrClosed},
+		{"dismissed", StatusDismissed, theirs, ErrClosed, ErrClosed},
+	}
+
+	for _, tt := range tests {
+		if err := CheckClaim(tt.status, tt.claimedBy, me); !errors.Is(err, tt.claimErr) {
+			t.Errorf("%s: CheckClaim = %v, want %v", tt.name, err, tt.claimErr)
+		}
+
+		if err := CheckClose(tt.status, tt.claimedBy, me); !errors.Is(err, tt.closeErr) {
+			t.Errorf("%s: CheckClose = %v, want %v", tt.name, err, tt.closeErr)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package roles defines what each kind of user may do on the admin API.
+package roles
+
+import "fmt"
+
+// Role is what kind of user someone is. Every account starts as User.
+type Role string
+
+const (
+	User      Role = "user"
+	Moderator Role = "moderator"
+	Admin     Role = "admin"
+)
+
+// Roles lists every role, least privileged first.
+var Roles = []Role{User, Moderator, Admin}
+
+// Permission is something a role may do.
+type Permission string
+
+const (
+	// ViewMetrics covers server and reaper metrics.
+	ViewMetrics Permission = "metrics:view"
+	// ReviewContent covers moderation flags, reports, restoring chirps and
+	// the audit trail.
+	ReviewContent Permission = "content:review"
+	// SuspendUsers covers suspending and unsuspending accounts.
+	SuspendUsers Permission = "users:suspend"
+	// ManageRoles covers changing roles.
+	ManageRoles Permission = "users:manage_roles"
+	// ManageBilling covers plan history and payment webhook events.
+	ManageBilling Permission = "billing:manage"
+)
+
+// Permissions lists every permission.
+var Permissions = []Permission{ViewMetrics, ReviewContent, SuspendUsers, ManageRoles, ManageBilling}
+
+// grants are the permissions of each role, admins have all of them
+var grants = map[Role]map[Permission]bool{
+	Moderator: {
+		ReviewContent: true,
+		SuspendUsers:  true,
+	},
+}
+
+// Parse returns the role named s.
+func Parse(s string) (Role, error) {
+	for _, role := range Roles {
+		if string(role) == s {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q", s)
+}
+
+// Can reports whether the role has permission p.
+func (r Role) Can(p Permission) bool {
+	if r == Admin {
+		return true
+	}
+	return grants[r][p]
+}
+
+// Staff reports whether the role has any access to the admin API.
+func (r Role) Staff() bool {
+	return r == Moderator || r == Admin
+}
+
+// CanSanction reports whether the role may suspend or warn a user with role
+// target. Moderators can sanction users, only admins can sanction staff.
+func (r Role) CanSanction(target Role) bool {
+	if !r.Can(SuspendUsers) {
+		return false
+	}
+	return !target.Staff() || r == Admin
+}
@@ -0,0 +1,65 @@
+package roles
+
+import "testing"
+
+func TestCan(t *testing.T) {
+	for _, p := range Permissions {
+		if User.Can(p) {
+			t.Errorf("user can %v", p)
+		}
+
+		if !Admin.Can(p) {
+			t.Errorf("admin can't %v", p)
+		}
+	}
+
+	for p, want := range map[Permission]bool{
+		ViewMetrics:   false,
+		ReviewContent: true,
+		SuspendUsers:  true,
+		ManageRoles:   false,
+		ManageBilling: false,
+	} {
+		if got := Moderator.Can(p); got != want {
+			t.Errorf("Moderator.Can(%v) = %v, want %v", p, got, want)
+		}
+	}
+
+	if Role("owner").Can(ReviewContent) {
+		t.Error("unknown role has a permission")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, role := range Roles {
+		if got, err := Parse(string(role)); err != nil || got != role {
+			t.Errorf("Parse(%q) = %q, %v", role, got, err)
+		}
+	}
+
+	for _, raw := range []string{"", "Admin", "owner"} {
+		if _, err := Parse(raw); err == nil {
+			t.Errorf("Parse(%q) succeeded", raw)
+		}
+	}
+}
+
+func TestCanSanction(t *testing.T) {
+	tests := []struct {
+		actor, target Role
+		want          bool
+	}{
+		{Moderator, User, true},
+		{Moderator, Moderator, false},
+		{Moderator, Admin, false},
+		{Admin, Moderator, true},
+		{Admin, Admin, true},
+		{User, User, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.actor.CanSanction(tt.target); got != tt.want {
+			t.Errorf("%v.CanSanction(%v) = %v, want %v", tt.actor, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+// Memory is a naive searcher that scans every chirp returned by Chirps. It
+// understands "quoted phrases" and -excluded terms, all other terms must
+// match. A term matches any word it is a prefix of, so "run" finds "running".
+type Memory struct {
+	Chirps func(ctx context.Context) ([]database.Chirp, error)
+}
+
+type token struct {
+	text  string
+	start int
+	end   int
+}
+
+type parsedQuery struct {
+	// every phrase must match, a plain term is a phrase of one word
+	phrases  [][]string
+	excluded []string
+}
+
+func (m Memory) Search(ctx context.Context, query string, opts Options) ([]Result, error) {
+
+	parsed := parseQuery(query)
+
+	if len(parsed.phrases) == 0 {
+		return []Result{}, nil
+	}
+
+	chirps, err := m.Chirps(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+
+	for _, chirp := range chirps {
+		if opts.AuthorID != uuid.Nil && chirp.UserID != opts.AuthorID {
+			continue
+		}
+		if !opts.Since.IsZero() && chirp.CreatedAt.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && !chirp.CreatedAt.Before(opts.Until) {
+			continue
+		}
+
+		tokens := tokenize(chirp.Body)
+		matched, ok := parsed.match(tokens)
+
+		if !ok {
+			continue
+		}
+
+		results = append(results, Result{
+			Chirp:   chirp,
+			Rank:    float64(len(matched)) / math.Sqrt(float64(len(tokens))),
+			Snippet: highlight(chirp.Body, tokens, matched),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if opts.Order != OrderRecency && a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.Chirp.CreatedAt.Equal(b.Chirp.CreatedAt) {
+			return a.Chirp.CreatedAt.After(b.Chirp.CreatedAt)
+		}
+		return bytes.Compare(a.Chirp.ID[:], b.Chirp.ID[:]) > 0
+	})
+
+	if opts.Offset >= len(results) {
+		return []Result{}, nil
+	}
+	results = results[opts.Offset:]
+
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+func parseQuery(query string) parsedQuery {
+	var parsed parsedQuery
+
+	for i, part := range strings.Split(query, `"`) {
+		// odd parts sit between a pair of quotes
+		if i%2 == 1 {
+			words := words(part)
+			if len(words) > 0 {
+				parsed.phrases = append(parsed.phrases, words)
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			exclude := strings.HasPrefix(field, "-")
+
+			for _, word := range words(field) {
+				if exclude {
+					parsed.excluded = append(parsed.excluded, word)
+					continue
+				}
+				parsed.phrases = append(parsed.phrases, []string{word})
+			}
+		}
+	}
+
+	return parsed
+}
+
+// match returns the indexes of the matching tokens, ok is false when a
+// phrase is missing or an excluded term is present
+func (p parsedQuery) match(tokens []token) (map[int]bool, bool) {
+	matched := map[int]bool{}
+
+	for _, excluded := range p.excluded {
+		for _, t := range tokens {
+			if strings.HasPrefix(t.text, excluded) {
+				return nil, false
+			}
+		}
+	}
+
+	for _, phrase := range p.phrases {
+		found := false
+
+		for start := 0; start+len(phrase) <= len(tokens); start++ {
+			ok := true
+			for offset, word := range phrase {
+				if !strings.HasPrefix(tokens[start+offset].text, word) {
+					ok = false
+					break
+				}
+			}
+
+			if !ok {
+				continue
+			}
+
+			found = true
+			for offset := range phrase {
+				matched[start+offset] = true
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return matched, true
+}
+
+// highlight escapes the body and wraps the matched tokens in <mark></mark>
+func highlight(body string, tokens []token, matched map[int]bool) string {
+	var b strings.Builder
+
+	last := 0
+	for i, t := range tokens {
+		if !matched[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(body[last:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(body[t.start:t.end]))
+		b.WriteString("</mark>")
+		last = t.end
+	}
+	b.WriteString(html.EscapeString(body[last:]))
+
+	return b.String()
+}
+
+func tokenize(body string) []token {
+	var tokens []token
+
+	start := -1
+	for i, r := range body {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{text: strings.ToLower(body[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(body[start:]), start: start, end: len(body)})
+	}
+
+	return tokens
+}
+
+func words(s string) []string {
+	var words []string
+	for _, t := range tokenize(s) {
+		words = append(words, t.text)
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestMemorySearch(t *testing.T) {
+	alice := uuid.New()
+	bob := uuid.New()
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	chirps := []database.Chirp{
+		{ID: uuid.New(), UserID: alice, CreatedAt: start, Body: "Going running in the park"},
+		{ID: uuid.New(), UserID: bob, CreatedAt: start.Add(time.Hour), Body: "The park is closed, no running today"},
+		{ID: uuid.New(), UserID: alice, CreatedAt: start.Add(2 * time.Hour), Body: "Running <fast> running far running"},
+		{ID: uuid.New(), UserID: bob, CreatedAt: start.Add(3 * time.Hour), Body: "Nothing to see here"},
+	}
+
+	memory := Memory{
+		Chirps: func(ctx context.Context) ([]database.Chirp, error) {
+			return chirps, nil
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		opts  Options
+		want  []database.Chirp
+	}{
+		{
+			name:  "Prefix term, most relevant first",
+			query: "run",
+			want:  []database.Chirp{chirps[2], chirps[0], chirps[1]},
+		},
+		{
+			name:  "Recency order",
+			query: "run",
+			opts:  Options{Order: OrderRecency},
+			want:  []database.Chirp{chirps[2], chirps[1], chirps[0]},
+		},
+		{
+			name:  "Phrase must match in order",
+			query: `"the park"`,
+			want:  []database.Chirp{chirps[0], chirps[1]},
+		},
+		{
+			name:  "Excluded term",
+			query: "park -closed",
+			want:  []database.Chirp{chirps[0]},
+		},
+		{
+			name:  "Author filter",
+			query: "running",
+			opts:  Options{AuthorID: bob},
+			want:  []database.Chirp{chirps[1]},
+		},
+		{
+			name:  "Date range",
+			query: "running",
+			opts:  Options{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)},
+			want:  []database.Chirp{chirps[1]},
+		},
+		{
+			name:  "Offset past the results",
+			query: "running",
+			opts:  Options{Offset: 10},
+			want:  []database.Chirp{},
+		},
+		{
+			name:  "Limit",
+			query: "running",
+			opts:  Options{Order: OrderRecency, Limit: 1, Offset: 1},
+			want:  []database.Chirp{chirps[1]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := memory.Search(context.Background(), tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search() returned %d results, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Chirp.ID != tt.want[i].ID {
+					t.Errorf("Search()[%d] = %q, want %q", i, got[i].Chirp.Body, tt.want[i].Body)
+				}
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	body := "Running <fast> & running"
+	tokens := tokenize(body)
+	matched, ok := parseQuery("run").match(tokens)
+	if !ok {
+		t.Fatal("match() = false, want true")
+	}
+
+	want := "<mark>Running</mark> &lt;fast&gt; &amp; <mark>running</mark>"
+	if got := highlight(body, tokens, matched); got != want {
+		t.Errorf("highlight() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+type Order string
+
+const (
+	OrderRelevance Order = "relevance"
+	OrderRecency   Order = "recency"
+)
+
+// Options narrow down and page through a search. Zero values mean no filter.
+type Options struct {
+	AuthorID uuid.UUID
+	Since    time.Time
+	Until    time.Time
+	Order    Order
+	Limit    int
+	Offset   int
+}
+
+// Result is a matching chirp with its relevance and a snippet of the body.
+// The snippet is html escaped with matches wrapped in <mark></mark>.
+type Result struct {
+	Chirp   database.Chirp
+	Rank    float64
+	Snippet string
+}
+
+type Searcher interface {
+	Search(ctx context.Context, query string, opts Options) ([]Result, error)
+}
+
+// Postgres searches the chirps table through its tsvector GIN index.
+type Postgres struct {
+	DB *database.Queries
+}
+
+func (p Postgres) Search(ctx context.Context, query string, opts Options) ([]Result, error) {
+
+	params := database.SearchChirpsParams{
+		Query:       query,
+		ByRelevance: opts.Order != OrderRecency,
+		PageSize:    int32(opts.Limit),
+		PageOffset:  int32(opts.Offset),
+	}
+
+	if opts.AuthorID != uuid.Nil {
+		params.AuthorID = uuid.NullUUID{UUID: opts.AuthorID, Valid: true}
+	}
+
+	if !opts.Since.IsZero() {
+		params.Since = sql.NullTime{Time: opts.Since.UTC(), Valid: true}
+	}
+
+	if !opts.Until.IsZero() {
+		params.Until = sql.NullTime{Time: opts.Until.UTC(), Valid: true}
+	}
+
+	rows, err := p.DB.SearchChirps(ctx, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(rows))
+
+	for _, row := range rows {
+		results = append(results, Result{
+			Chirp:   row.Chirp,
+			Rank:    float64(row.Rank),
+			Snippet: row.Snippet,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,188 @@
+package stream
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a published event. IDs are "<epoch>-<seq>", the epoch changes
+// every time the process starts so IDs from before a restart are never
+// mistaken for current ones.
+type Event struct {
+	ID        string
+	Type      string
+	SubjectID uuid.UUID
+	Data      json.RawMessage
+	Time      time.Time
+
+	seq uint64
+}
+
+// Broker fans events out to subscribers and keeps the most recent ones so
+// a subscriber that reconnects can resume where it left off.
+type Broker struct {
+	// channel buffer per subscriber, a subscriber that falls this far
+	// behind is dropped
+	SubscriberBuffer int
+
+	mu     sync.Mutex
+	epoch  string
+	seq    uint64
+	size   int
+	buffer []Event
+	subs   map[*Subscription]struct{}
+}
+
+// Subscription receives matching events on C until it is closed, either by
+// Close or by the broker when the subscriber can't keep up.
+type Subscription struct {
+	C <-chan Event
+
+	c       chan Event
+	match   func(Event) bool
+	broker  *Broker
+	dropped bool
+}
+
+func NewBroker(bufferSize int) *Broker {
+	epoch := make([]byte, 4)
+	rand.Read(epoch)
+
+	return &Broker{
+		SubscriberBuffer: 64,
+		epoch:            hex.EncodeToString(epoch),
+		size:             bufferSize,
+		subs:             map[*Subscription]struct{}{},
+	}
+}
+
+// Publish sends an event about subjectID, e.g. a chirp's author, to every
+// matching subscriber.
+func (b *Broker) Publish(eventType string, subjectID uuid.UUID, data any) error {
+
+	payload, err := json.Marshal(data)
+
+	if err != nil {
+		return fmt.Errorf("marshal %v event: %w", eventType, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := Event{
+		ID:        fmt.Sprintf("%v-%d", b.epoch, b.seq),
+		Type:      eventType,
+		SubjectID: subjectID,
+		Data:      payload,
+		Time:      time.Now(),
+		seq:       b.seq,
+	}
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.size {
+		b.buffer = append(b.buffer[:0], b.buffer[len(b.buffer)-b.size:]...)
+	}
+
+	for sub := range b.subs {
+		if !sub.match(event) {
+			continue
+		}
+
+		select {
+		case sub.c <- event:
+		default:
+			// never block publishers on a slow subscriber
+			b.drop(sub, true)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe starts receiving events accepted by match. When lastEventID is
+// set, the buffered events after it are returned for replay; complete is
+// false when events may have been missed because lastEventID is older than
+// the buffer or from before a restart.
+func (b *Broker) Subscribe(lastEventID string, match func(Event) bool) (sub *Subscription, replay []Event, complete bool) {
+
+	if match == nil {
+		match = func(Event) bool { return true }
+	}
+
+	c := make(chan Event, b.SubscriberBuffer)
+	sub = &Subscription{C: c, c: c, match: match, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	seq, ok := b.parseID(lastEventID)
+
+	complete = ok && (seq == b.seq || (len(b.buffer) > 0 && seq+1 >= b.buffer[0].seq))
+
+	for _, event := range b.buffer {
+		if ok && event.seq <= seq {
+			continue
+		}
+		if match(event) {
+			replay = append(replay, event)
+		}
+	}
+
+	return sub, replay, complete
+}
+
+func (b *Broker) parseID(id string) (uint64, bool) {
+	epoch, rawSeq, found := strings.Cut(id, "-")
+
+	if !found || epoch != b.epoch {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(rawSeq, 10, 64)
+
+	if err != nil || seq > b.seq {
+		return 0, false
+	}
+
+	return seq, true
+}
+
+// drop must be called with b.mu held
+func (b *Broker) drop(sub *Subscription, lagged bool) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.dropped = lagged
+	close(sub.c)
+}
+
+// Close unsubscribes, C is closed.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s, false)
+}
+
+// Dropped reports whether the broker closed the subscription because the
+// subscriber fell behind.
+func (s *Subscription) Dropped() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	default:
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	broker := NewBroker(10)
+	author, other := uuid.New(), uuid.New()
+
+	all, _, _ := broker.Subscribe("", nil)
+	byAuthor, _, _ := broker.Subscribe("", func(e Event) bool { return e.SubjectID == author })
+
+	broker.Publish("chirp.created", other, map[string]string{"body": "first"})
+	broker.Publish("chirp.created", author, map[string]string{"body": "second"})
+
+	if got := receive(t, all); string(got.Data) != `{"body":"first"}` {
+		t.Errorf("first event data = %s", got.Data)
+	}
+	receive(t, all)
+
+	if got := receive(t, byAuthor); got.SubjectID != author {
+		t.Errorf("filtered subscriber got an event about %v", got.SubjectID)
+	}
+
+	all.Close()
+	if _, open := <-all.C; open {
+		t.Error("channel still open after Close")
+	}
+	if all.Dropped() {
+		t.Error("closed subscription reported as dropped")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	broker := NewBroker(3)
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		broker.Publish("chirp.created", uuid.New(), i)
+		ids = append(ids, broker.buffer[len(broker.buffer)-1].ID)
+	}
+
+	tests := []struct {
+		name         string
+		lastEventID  string
+		wantReplay   int
+		wantComplete bool
+	}{
+		{name: "Up to date", lastEventID: ids[4], wantReplay: 0, wantComplete: true},
+		{name: "Within buffer", lastEventID: ids[2], wantReplay: 2, wantComplete: true},
+		{name: "Just before buffer", lastEventID: ids[1], wantReplay: 3, wantComplete: true},
+		{name: "Older than buffer", lastEventID: ids[0], wantReplay: 3, wantComplete: false},
+		{name: "From before a restart", lastEventID: "00000000-3", wantReplay: 3, wantComplete: false},
+		{name: "Garbage", lastEventID: "nope", wantReplay: 3, wantComplete: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, replay, complete := broker.Subscribe(tt.lastEventID, nil)
+			defer sub.Close()
+
+			if len(replay) != tt.wantReplay || complete != tt.wantComplete {
+				t.Errorf("Subscribe(%q) replayed %d, complete %v, want %d, %v", tt.lastEventID, len(replay), complete, tt.wantReplay, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	broker := NewBroker(10)
+	broker.SubscriberBuffer = 2
+
+	sub, _, _ := broker.Subscribe("", nil)
+
+	for i := 0; i < 3; i++ {
+		broker.Publish("chirp.created", uuid.New(), i)
+	}
+
+	if !sub.Dropped() {
+		t.Fatal("subscriber that fell behind wasn't dropped")
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("received %d buffered events, want 2", received)
+	}
+}
@@ -0,0 +1,20 @@
+package webhooks
+
+import "time"
+
+// InboundLease is how long a received event may be processing. Past it the
+// process handling it is assumed to have died, or failed to save the
+// result, and a retry or replay may process the event again.
+const InboundLease = 5 * time.Minute
+
+// Abandoned reports whether a received event has been processing since
+// startedAt for longer than lease.
+func Abandoned(status string, startedAt, now time.Time, lease time.Duration) bool {
+	return status == "processing" && !now.Before(startedAt.Add(lease))
+}
+
+// StaleBefore is the start time before which processing events are
+// abandoned.
+func StaleBefore(now time.Time, lease time.Duration) time.Time {
+	return now.Add(-lease)
+}
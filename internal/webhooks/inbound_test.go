@@ -0,0 +1,36 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAbandoned(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		status    string
+		startedAt time.Time
+		want      bool
+	}{
+		{"just started", "processing", now.Add(-time.Second), false},
+		{"within the lease", "processing", now.Add(-InboundLease + time.Second), false},
+		{"lease ran out", "processing", now.Add(-InboundLease), true},
+		{"long abandoned", "processing", now.Add(-24 * time.Hour), true},
+		{"failed", "failed", now.Add(-24 * time.Hour), false},
+		{"processed", "processed", now.Add(-24 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if got := Abandoned(tt.status, tt.startedAt, now, InboundLease); got != tt.want {
+			t.Errorf("%s: Abandoned = %v, want %v", tt.name, got, tt.want)
+		}
+
+		// the database claims by StaleBefore, it has to agree
+		stale := tt.status == "processing" && !tt.startedAt.After(StaleBefore(now, InboundLease))
+		if stale != tt.want {
+			t.Errorf("%s: StaleBefore disagrees with Abandoned", tt.name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/database"
+	"github.com/google/uuid"
+)
+
+// Postgres stores webhooks in the webhook_endpoints, webhook_deliveries and
+// webhook_delivery_attempts tables. Built on a transaction's Queries,
+// Enqueue only queues the event if the transaction commits.
+type Postgres struct {
+	DB *database.Queries
+}
+
+func (p Postgres) Enqueue(ctx context.Context, eventID uuid.UUID, eventType string, ownerID uuid.NullUUID, payload []byte) error {
+	return p.DB.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   string(payload),
+		OwnerID:   ownerID,
+	})
+}
+
+func (p Postgres) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error) {
+
+	rows, err := p.DB.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		Now:        now,
+		LeaseUntil: leaseUntil,
+		Limit:      int32(limit),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]Delivery, 0, len(rows))
+
+	for _, row := range rows {
+		deliveries = append(deliveries, Delivery{
+			ID:         row.ID,
+			EndpointID: row.EndpointID,
+			EventID:    row.EventID,
+			EventType:  row.EventType,
+			Payload:    []byte(row.Payload),
+			Attempts:   int(row.Attempts),
+			URL:        row.Url,
+			Secret:     row.Secret,
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (p Postgres) RecordAttempt(ctx context.Context, attempt Attempt) error {
+	return p.DB.RecordWebhookAttempt(ctx, database.RecordWebhookAttemptParams{
+		DeliveryID:   attempt.DeliveryID,
+		Attempt:      int32(attempt.Attempt),
+		StatusCode:   int32(attempt.StatusCode),
+		Error:        attempt.Error,
+		ResponseBody: attempt.ResponseBody,
+		DurationMs:   int32(attempt.Duration.Milliseconds()),
+	})
+}
+
+func (p Postgres) Delivered(ctx context.Context, delivery Delivery) error {
+	return p.DB.CompleteWebhookDelivery(ctx, database.CompleteWebhookDeliveryParams{
+		ID:     delivery.ID,
+		Status: "delivered",
+	})
+}
+
+func (p Postgres) Retry(ctx context.Context, delivery Delivery, next time.Time) error {
+	return p.DB.RetryWebhookDelivery(ctx, database.RetryWebhookDeliveryParams{
+		ID:            delivery.ID,
+		NextAttemptAt: next,
+	})
+}
+
+func (p Postgres) Failed(ctx context.Context, delivery Delivery) error {
+	return p.DB.CompleteWebhookDelivery(ctx, database.CompleteWebhookDeliveryParams{
+		ID:     delivery.ID,
+		Status: "failed",
+	})
+}
+
+func (p Postgres) EndpointFailed(ctx context.Context, endpointID uuid.UUID, disableAfter int) (bool, error) {
+	return p.DB.RecordWebhookEndpointFailure(ctx, database.RecordWebhookEndpointFailureParams{
+		ID:           endpointID,
+		DisableAfter: int32(disableAfter),
+	})
+}
+
+func (p Postgres) EndpointSucceeded(ctx context.Context, endpointID uuid.UUID) error {
+	return p.DB.ResetWebhookEndpointFailures(ctx, endpointID)
+}
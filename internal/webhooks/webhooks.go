@@ -0,0 +1,315 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/netguard"
+	"github.com/google/uuid"
+)
+
+// Event types endpoints can subscribe to.
+const (
+	ChirpCreated   = "chirp.created"
+	ChirpUpdated   = "chirp.updated"
+	ChirpDeleted   = "chirp.deleted"
+	UserUpgraded   = "user.upgraded"
+	UserDowngraded = "user.downgraded"
+)
+
+var EventTypes = []string{ChirpCreated, ChirpUpdated, ChirpDeleted, UserUpgraded, UserDowngraded}
+
+// Private reports whether events of eventType are only sent to endpoints of
+// the user they are about. Chirps are public, account changes aren't.
+func Private(eventType string) bool {
+	return strings.HasPrefix(eventType, "user.")
+}
+
+// Event is the JSON body of every delivery.
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Delivery is one event on its way to one endpoint. Attempts counts the
+// attempts made before this one.
+type Delivery struct {
+	ID         uuid.UUID
+	EndpointID uuid.UUID
+	EventID    uuid.UUID
+	EventType  string
+	Payload    []byte
+	Attempts   int
+	URL        string
+	Secret     string
+}
+
+// Attempt is the log entry of one try at a delivery. StatusCode is 0 when
+// no response was received.
+type Attempt struct {
+	DeliveryID   uuid.UUID
+	Attempt      int
+	StatusCode   int
+	Error        string
+	ResponseBody string
+	Duration     time.Duration
+}
+
+// Store persists endpoints, queued deliveries and the attempt log.
+type Store interface {
+	// Enqueue queues an event for every enabled endpoint subscribed to it,
+	// only the endpoints of ownerID when it is set.
+	Enqueue(ctx context.Context, eventID uuid.UUID, eventType string, ownerID uuid.NullUUID, payload []byte) error
+	// Claim hands out up to limit deliveries due by now, hiding them from
+	// other claims until leaseUntil.
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error)
+	RecordAttempt(ctx context.Context, attempt Attempt) error
+	Delivered(ctx context.Context, delivery Delivery) error
+	Retry(ctx context.Context, delivery Delivery, next time.Time) error
+	Failed(ctx context.Context, delivery Delivery) error
+	// EndpointFailed counts a failed attempt against the endpoint and
+	// reports whether it is still enabled.
+	EndpointFailed(ctx context.Context, endpointID uuid.UUID, disableAfter int) (bool, error)
+	EndpointSucceeded(ctx context.Context, endpointID uuid.UUID) error
+}
+
+// Emit queues an event of eventType about subjectID carrying data. Private
+// events only go to the subject's own endpoints.
+func Emit(ctx context.Context, store Store, eventType string, subjectID uuid.UUID, data any) error {
+
+	event := Event{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	payload, err := json.Marshal(event)
+
+	if err != nil {
+		return fmt.Errorf("marshal %v event: %w", eventType, err)
+	}
+
+	var ownerID uuid.NullUUID
+	if Private(eventType) {
+		ownerID = uuid.NullUUID{UUID: subjectID, Valid: true}
+	}
+
+	return store.Enqueue(ctx, event.ID, eventType, ownerID, payload)
+}
+
+// Backoff waits base*2^(attempt-1), capped at max, with up to 20% jitter
+// so endpoints that come back up aren't hit by every retry at once.
+func Backoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		wait := base << (attempt - 1)
+		if wait > max || wait <= 0 {
+			wait = max
+		}
+		return wait - time.Duration(rand.Float64()*0.2*float64(wait))
+	}
+}
+
+// Worker sends queued deliveries. Zero fields get defaults.
+type Worker struct {
+	Store  Store
+	Client *http.Client
+	// deliveries claimed per poll
+	BatchSize int
+	// a delivery fails for good after MaxAttempts
+	MaxAttempts int
+	// an endpoint is disabled after DisableAfter failed attempts in a row
+	DisableAfter int
+	Backoff      func(attempt int) time.Duration
+	// how long a claimed delivery is hidden from other workers
+	Lease time.Duration
+	Now   func() time.Time
+	// AllowPrivate lets the default client reach internal addresses, only
+	// for tests and local development
+	AllowPrivate bool
+}
+
+const maxResponseBody = 1024
+
+func (w *Worker) defaults() {
+	if w.Client == nil {
+		w.Client = newClient(10*time.Second, w.AllowPrivate)
+	}
+	if w.BatchSize == 0 {
+		w.BatchSize = 20
+	}
+	if w.MaxAttempts == 0 {
+		w.MaxAttempts = 8
+	}
+	if w.DisableAfter == 0 {
+		w.DisableAfter = 20
+	}
+	if w.Backoff == nil {
+		w.Backoff = Backoff(30*time.Second, 6*time.Hour)
+	}
+	if w.Lease == 0 {
+		w.Lease = time.Minute
+	}
+	if w.Now == nil {
+		w.Now = time.Now
+	}
+}
+
+// newClient refuses to connect to internal addresses, endpoints are user
+// supplied and the response is shown to their owner. Redirects aren't
+// followed, a 3xx is a failed attempt.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			//a proxy would do the dialing for us
+			Proxy:                 nil,
+			DialContext:           netguard.Dialer(timeout, allowPrivate).DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run polls for due deliveries every interval until ctx is done.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// keep going while there is a backlog
+		for {
+			sent, err := w.RunOnce(ctx)
+
+			if err != nil {
+				log.Printf("webhooks: %v", err)
+			}
+
+			if err != nil || sent < w.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of due deliveries and returns how many it tried.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	w.defaults()
+
+	now := w.Now()
+
+	deliveries, err := w.Store.Claim(ctx, now, now.Add(w.Lease), w.BatchSize)
+
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if err := w.deliver(ctx, delivery); err != nil {
+			log.Printf("webhooks: delivery %v: %v", delivery.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery Delivery) error {
+
+	attempt := Attempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts + 1,
+	}
+
+	start := w.Now()
+	statusCode, body, err := w.send(ctx, delivery)
+	attempt.Duration = w.Now().Sub(start)
+
+	attempt.StatusCode = statusCode
+	attempt.ResponseBody = body
+
+	ok := err == nil && statusCode >= 200 && statusCode < 300
+
+	if err != nil {
+		attempt.Error = err.Error()
+	} else if !ok {
+		attempt.Error = fmt.Sprintf("endpoint responded %d", statusCode)
+	}
+
+	if err := w.Store.RecordAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("record attempt: %w", err)
+	}
+
+	if ok {
+		if err := w.Store.EndpointSucceeded(ctx, delivery.EndpointID); err != nil {
+			return err
+		}
+		return w.Store.Delivered(ctx, delivery)
+	}
+
+	enabled, err := w.Store.EndpointFailed(ctx, delivery.EndpointID, w.DisableAfter)
+
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		log.Printf("webhooks: disabled endpoint %v after %d failed attempts in a row", delivery.EndpointID, w.DisableAfter)
+	}
+
+	if attempt.Attempt >= w.MaxAttempts {
+		return w.Store.Failed(ctx, delivery)
+	}
+
+	// deliveries of a disabled endpoint stay queued until it is re-enabled
+	return w.Store.Retry(ctx, delivery, w.Now().Add(w.Backoff(attempt.Attempt)))
+}
+
+func (w *Worker) send(ctx context.Context, delivery Delivery) (int, string, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set("Webhook-Id", delivery.EventID.String())
+	req.Header.Set("Webhook-Event", delivery.EventType)
+	req.Header.Set(auth.WebhookSignatureHeader, auth.SignWebhook(delivery.Secret, w.Now(), delivery.Payload))
+
+	res, err := w.Client.Do(req)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+
+	return res.StatusCode, string(body), nil
+}
@@ -0,0 +1,365 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/google/uuid"
+)
+
+type memEndpoint struct {
+	owner    uuid.UUID
+	url      string
+	secret   string
+	events   []string
+	enabled  bool
+	failures int
+}
+
+type memDelivery struct {
+	Delivery
+	status string
+	next   time.Time
+}
+
+// memStore is an in-memory Store for exercising the Worker
+type memStore struct {
+	mu         sync.Mutex
+	endpoints  map[uuid.UUID]*memEndpoint
+	deliveries []*memDelivery
+	attempts   []Attempt
+}
+
+func newMemStore() *memStore {
+	return &memStore{endpoints: map[uuid.UUID]*memEndpoint{}}
+}
+
+func (s *memStore) addEndpoint(url, secret string, events ...string) uuid.UUID {
+	id := uuid.New()
+	s.endpoints[id] = &memEndpoint{url: url, secret: secret, events: events, enabled: true}
+	return id
+}
+
+func (s *memStore) Enqueue(ctx context.Context, eventID uuid.UUID, eventType string, ownerID uuid.NullUUID, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, endpoint := range s.endpoints {
+		if !endpoint.enabled || !slices.Contains(endpoint.events, eventType) {
+			continue
+		}
+		if ownerID.Valid && endpoint.owner != ownerID.UUID {
+			continue
+		}
+		s.deliveries = append(s.deliveries, &memDelivery{
+			Delivery: Delivery{
+				ID:         uuid.New(),
+				EndpointID: id,
+				EventID:    eventID,
+				EventType:  eventType,
+				Payload:    payload,
+				URL:        endpoint.url,
+				Secret:     endpoint.secret,
+			},
+			status: "pending",
+		})
+	}
+	return nil
+}
+
+func (s *memStore) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []Delivery
+	for _, d := range s.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if d.status != "pending" || d.next.After(now) || !s.endpoints[d.EndpointID].enabled {
+			continue
+		}
+		d.next = leaseUntil
+		claimed = append(claimed, d.Delivery)
+	}
+	return claimed, nil
+}
+
+func (s *memStore) RecordAttempt(ctx context.Context, attempt Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, attempt)
+	return nil
+}
+
+func (s *memStore) find(id uuid.UUID) *memDelivery {
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *memStore) complete(delivery Delivery, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.find(delivery.ID)
+	d.status = status
+	d.Attempts++
+	return nil
+}
+
+func (s *memStore) Delivered(ctx context.Context, delivery Delivery) error {
+	return s.complete(delivery, "delivered")
+}
+
+func (s *memStore) Failed(ctx context.Context, delivery Delivery) error {
+	return s.complete(delivery, "failed")
+}
+
+func (s *memStore) Retry(ctx context.Context, delivery Delivery, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.find(delivery.ID)
+	d.Attempts++
+	d.next = next
+	return nil
+}
+
+func (s *memStore) EndpointFailed(ctx context.Context, endpointID uuid.UUID, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint := s.endpoints[endpointID]
+	endpoint.failures++
+	if endpoint.failures >= disableAfter {
+		endpoint.enabled = false
+	}
+	return endpoint.enabled, nil
+}
+
+func (s *memStore) EndpointSucceeded(ctx context.Context, endpointID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints[endpointID].failures = 0
+	return nil
+}
+
+func TestDeliverSigned(t *testing.T) {
+	now := time.Now()
+
+	var received Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		err := auth.VerifyWebhookSignature(r.Header.Get(auth.WebhookSignatureHeader), body, []string{"secret"}, time.Minute, now)
+		if err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		if got := r.Header.Get("Webhook-Event"); got != ChirpCreated {
+			t.Errorf("Webhook-Event = %q, want %q", got, ChirpCreated)
+		}
+
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := newMemStore()
+	store.addEndpoint(receiver.URL, "secret", ChirpCreated)
+	store.addEndpoint(receiver.URL, "other", ChirpDeleted)
+
+	if err := Emit(context.Background(), store, ChirpCreated, uuid.New(), map[string]string{"body": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	worker := &Worker{Store: store, Now: func() time.Time { return now }, AllowPrivate: true}
+
+	sent, err := worker.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 {
+		t.Fatalf("sent %d deliveries, want 1", sent)
+	}
+
+	if received.Type != ChirpCreated || received.Data.(map[string]any)["body"] != "hi" {
+		t.Errorf("received %+v", received)
+	}
+
+	if status := store.deliveries[0].status; status != "delivered" {
+		t.Errorf("status = %q, want delivered", status)
+	}
+
+	if len(store.attempts) != 1 || store.attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("attempts = %+v", store.attempts)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	now := time.Now()
+
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	store := newMemStore()
+	store.addEndpoint(receiver.URL, "secret", ChirpCreated)
+	Emit(context.Background(), store, ChirpCreated, uuid.New(), nil)
+
+	worker := &Worker{
+		Store:        store,
+		MaxAttempts:  3,
+		Backoff:      func(attempt int) time.Duration { return time.Duration(attempt) * time.Minute },
+		Now:          func() time.Time { return now },
+		AllowPrivate: true,
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if sent, _ := worker.RunOnce(context.Background()); sent != 1 {
+			t.Fatalf("attempt %d: sent %d deliveries, want 1", attempt, sent)
+		}
+
+		// nothing is due again until the backoff has passed
+		if sent, _ := worker.RunOnce(context.Background()); sent != 0 {
+			t.Fatalf("attempt %d: retried before the backoff", attempt)
+		}
+
+		now = now.Add(time.Duration(attempt) * time.Minute)
+	}
+
+	if calls != 3 {
+		t.Errorf("receiver called %d times, want 3", calls)
+	}
+
+	if status := store.deliveries[0].status; status != "failed" {
+		t.Errorf("status = %q, want failed", status)
+	}
+
+	last := store.attempts[len(store.attempts)-1]
+	if last.Attempt != 3 || last.StatusCode != http.StatusServiceUnavailable || last.ResponseBody != "down for maintenance\n" {
+		t.Errorf("last attempt = %+v", last)
+	}
+}
+
+func TestDisableEndpoint(t *testing.T) {
+	now := time.Now()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := newMemStore()
+	endpointID := store.addEndpoint(receiver.URL, "secret", ChirpCreated)
+	Emit(context.Background(), store, ChirpCreated, uuid.New(), nil)
+	Emit(context.Background(), store, ChirpCreated, uuid.New(), nil)
+
+	worker := &Worker{
+		Store:        store,
+		DisableAfter: 2,
+		Backoff:      func(int) time.Duration { return 0 },
+		Now:          func() time.Time { return now },
+		AllowPrivate: true,
+	}
+
+	worker.RunOnce(context.Background())
+
+	if store.endpoints[endpointID].enabled {
+		t.Fatal("endpoint still enabled after 2 failed attempts")
+	}
+
+	if sent, _ := worker.RunOnce(context.Background()); sent != 0 {
+		t.Errorf("sent %d deliveries to a disabled endpoint", sent)
+	}
+
+	// queued deliveries wait for the endpoint to be re-enabled
+	for _, d := range store.deliveries {
+		if d.status != "pending" {
+			t.Errorf("delivery status = %q, want pending", d.status)
+		}
+	}
+}
+
+func TestPrivateEvents(t *testing.T) {
+	store := newMemStore()
+
+	subject := uuid.New()
+	mine := store.addEndpoint("https://example.com/mine", "secret", ChirpCreated, UserUpgraded)
+	store.endpoints[mine].owner = subject
+	theirs := store.addEndpoint("https://example.com/theirs", "secret", ChirpCreated, UserUpgraded)
+	store.endpoints[theirs].owner = uuid.New()
+
+	Emit(context.Background(), store, UserUpgraded, subject, nil)
+
+	if len(store.deliveries) != 1 || store.deliveries[0].EndpointID != mine {
+		t.Fatalf("user.upgraded queued for %d endpoints, want only the subject's", len(store.deliveries))
+	}
+
+	Emit(context.Background(), store, ChirpCreated, subject, nil)
+
+	if len(store.deliveries) != 3 {
+		t.Errorf("chirp.created queued for %d endpoints, want every subscriber", len(store.deliveries)-1)
+	}
+}
+
+func TestRefuseInternal(t *testing.T) {
+	now := time.Now()
+
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("internal secrets"))
+	}))
+	defer receiver.Close()
+
+	store := newMemStore()
+	store.addEndpoint(receiver.URL, "secret", ChirpCreated)
+	Emit(context.Background(), store, ChirpCreated, uuid.New(), nil)
+
+	worker := &Worker{Store: store, Now: func() time.Time { return now }}
+
+	if sent, _ := worker.RunOnce(context.Background()); sent != 1 {
+		t.Fatalf("sent %d deliveries, want 1", sent)
+	}
+
+	if calls != 0 {
+		t.Errorf("receiver on loopback called %d times", calls)
+	}
+
+	if len(store.attempts) != 1 || store.attempts[0].StatusCode != 0 || store.attempts[0].ResponseBody != "" {
+		t.Errorf("attempts = %+v", store.attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	backoff := Backoff(time.Second, time.Minute)
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 10, max: time.Minute},
+		{attempt: 100, max: time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := backoff(tt.attempt)
+		if got > tt.max || got < tt.max*8/10 {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max*8/10, tt.max)
+		}
+	}
+}
@@ -1,43 +1,116 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"sort"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/JonMunkholm/server/internal/auth"
+	"github.com/JonMunkholm/server/internal/blob"
+	"github.com/JonMunkholm/server/internal/chirptext"
 	"github.com/JonMunkholm/server/internal/database"
+	"github.com/JonMunkholm/server/internal/entitlements"
+	"github.com/JonMunkholm/server/internal/linkpreview"
+	"github.com/JonMunkholm/server/internal/media"
+	"github.com/JonMunkholm/server/internal/moderation"
+	"github.com/JonMunkholm/server/internal/notify"
+	"github.com/JonMunkholm/server/internal/ratelimit"
+	"github.com/JonMunkholm/server/internal/roles"
+	"github.com/JonMunkholm/server/internal/search"
+	"github.com/JonMunkholm/server/internal/stream"
+	"github.com/JonMunkholm/server/internal/webhooks"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const filepathRoot = "."
 const port = "8080"
 
+const (
+	chirpKindChirp   = "chirp"
+	chirpKindRechirp = "rechirp"
+	chirpKindQuote   = "quote"
+)
+
 
 type apiConfig struct {
 fileserverHits 	atomic.Int32
 db      		*database.Queries
+sqlDB           *sql.DB
 platform    	string
 secret  		string
 polkaKey        string
+polkaSecrets    []string
+polkaTolerance  time.Duration
+polkaAllowAPIKey bool
+timelineFanout  bool
+webhooksAllowPrivate bool
+searcher        search.Searcher
+broker          *stream.Broker
+moderation      *moderation.Pipeline
+plans           entitlements.Catalog
+urlWeight       int
+chirpRate       *ratelimit.Limiter
+blobs           blob.Store
+mediaProcessor  media.Processor
+mediaMaxBytes   int64
+mediaUnattachedTTL time.Duration
+linkPreviews    *linkPreviewer
+reaper          reaperMetrics
+//how long deleted chirps are kept before they are purged
+chirpRetention  time.Duration
 }
 
 type userPerams struct {
 	Password 	string  `json:"password"`
 	Email    	string  `json:"email"`
+	Handle    	string  `json:"handle"`
 }
 
 type makeChirpParams struct {
-	Body    	string  `json:"body"`
+	Body    			string  `json:"body"`
+	//set to rechirp (empty body) or quote (with body) another chirp
+	OriginalChirpID		string  `json:"original_chirp_id"`
+	InReplyToID			string  `json:"in_reply_to_id"`
+	MediaIDs			[]string  `json:"media_ids"`
+	Poll				*pollParams  `json:"poll,omitempty"`
+	//seconds until the chirp deletes itself
+	ExpiresIn			int  `json:"expires_in,omitempty"`
+}
+
+// a chirp that passed validation, ready to be stored
+type preparedChirp struct {
+	params		database.CreateChirpParams
+	plan		entitlements.Entitlements
+	verdict		moderation.Verdict
+	mediaIDs	[]uuid.UUID
+	//the chirp replied to, when params.InReplyToID is set
+	parent		database.Chirp
+	//normalized and moderated options, when the chirp has a poll
+	pollOptions	[]string
+	pollClosesAt	time.Time
+}
+
+// chirpError is a chirp that can't be posted, with the status to answer
+type chirpError struct {
+	status		int
+	message		string
+	retryAfter	time.Duration
+}
+
+func (e *chirpError) Error() string {
+	return e.message
 }
 
 type chirpResponse struct {
@@ -46,12 +119,34 @@ type chirpResponse struct {
 	UpdatedAt time.Time		`json:"updated_at"`
 	Body      string		`json:"body"`
 	UserID    uuid.UUID		`json:"user_id"`
+	Kind      string		`json:"kind"`
+	InReplyToID *uuid.UUID	`json:"in_reply_to_id,omitempty"`
+	LikeCount  int64		`json:"like_count"`
+	ReplyCount int64		`json:"reply_count"`
+	Entities  entitiesResponse		`json:"entities"`
+	Media     []mediaResponse		`json:"media,omitempty"`
+	LinkPreviews []linkPreviewResponse	`json:"link_previews,omitempty"`
+	Poll      *pollResponse		`json:"poll,omitempty"`
+	ExpiresAt *time.Time		`json:"expires_at,omitempty"`
+	//set on tombstones, which keep only the chirp's place in its thread
+	Deleted   bool		`json:"deleted,omitempty"`
+	Original  *originalChirpResponse	`json:"original,omitempty"`
+}
+
+// referenced chirp of a rechirp/quote, chirp fields are omitted once the original is gone
+type originalChirpResponse struct {
+	Available	bool	`json:"available"`
+	*chirpResponse
 }
 
 type isChirpRedWebhookRequest struct {
+	//unique per event, retries reuse it
+	ID    string `json:"id"`
 	Event string `json:"event"`
 	Data  struct {
 		UserID string `json:"user_id"`
+		//end of the paid period, optional
+		PeriodEnd time.Time `json:"period_end"`
 	} `json:"data"`
 }
 
@@ -60,6 +155,7 @@ type userInfoResponse struct {
 	CreatedAt 	time.Time `json:"created_at"`
 	UpdatedAt 	time.Time `json:"updated_at"`
 	Email     	string    `json:"email"`
+	Handle     	string    `json:"handle"`
 	IsChirpRed	bool	  `json:"is_chirpy_red"`
 }
 
@@ -70,7 +166,9 @@ type userSessionResponse struct {
 	Email     		string    `json:"email"`
 	Token	  		string	  `json:"token"`
 	RefreshToken 	string    `json:"refresh_token"`
+	Handle			string    `json:"handle"`
 	IsChirpRed		bool	  `json:"is_chirpy_red"`
+	Role			string    `json:"role"`
 }
 
 type refreshTokenResponse struct {
@@ -79,6 +177,8 @@ type refreshTokenResponse struct {
 
 type errResponse struct {
 	Error   string  `json:"error"`
+	//machine readable reason, for errors clients handle specifically
+	Code    string  `json:"code,omitempty"`
 }
 
 func main() {
@@ -109,10 +209,37 @@ func main() {
 		log.Fatal("JWT_SECRET must be set")
 	}
 
+	//webhooks are signed with any of the comma separated secrets, listing
+	//two lets Polka rotate without downtime
+	var polkaSecrets []string
+
+	for _, secret := range strings.Split(os.Getenv("POLKA_WEBHOOK_SECRETS"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			polkaSecrets = append(polkaSecrets, secret)
+		}
+	}
+
+	polkaTolerance := 5 * time.Minute
+
+	if rawTolerance := os.Getenv("POLKA_SIGNATURE_TOLERANCE"); rawTolerance != "" {
+		polkaTolerance, err = time.ParseDuration(rawTolerance)
+
+		if err != nil {
+			log.Fatal("Invalid POLKA_SIGNATURE_TOLERANCE: ", err)
+		}
+	}
+
+	//compatibility with the old Authorization: ApiKey scheme
+	polkaAllowAPIKey := os.Getenv("POLKA_ALLOW_API_KEY") == "true"
+
 	polka := os.Getenv("POLKA_KEY")
 
-	if jwtSecret == "" {
-		log.Fatal("POLKA_KEY must be set")
+	if polkaAllowAPIKey && polka == "" {
+		log.Fatal("POLKA_KEY must be set when POLKA_ALLOW_API_KEY is enabled")
+	}
+
+	if len(polkaSecrets) == 0 && !polkaAllowAPIKey {
+		log.Fatal("POLKA_WEBHOOK_SECRETS must be set")
 	}
 
 
@@ -121,16 +248,146 @@ func main() {
 	var apiConfig apiConfig
 
 	apiConfig.db = dbQueries
+	apiConfig.sqlDB = db
 	apiConfig.platform = platform
 	apiConfig.secret = jwtSecret
 	apiConfig.polkaKey = polka
+	apiConfig.polkaSecrets = polkaSecrets
+	apiConfig.polkaTolerance = polkaTolerance
+	apiConfig.polkaAllowAPIKey = polkaAllowAPIKey
+	//materialize home timelines on write instead of merging followed authors on read
+	apiConfig.timelineFanout = os.Getenv("TIMELINE_FANOUT") == "true"
+
+	//the first admin is named by email, later staff are appointed by admins
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		promoted, err := dbQueries.SetUserRoleByEmail(context.Background(), database.SetUserRoleByEmailParams{
+			Email: adminEmail,
+			Role: string(roles.Admin),
+		})
+
+		if err != nil {
+			log.Fatal("Failed to set up admin: ", err)
+		}
+
+		if promoted == 0 {
+			log.Printf("ADMIN_EMAIL %v has no account yet, restart after signing up", adminEmail)
+		}
+	}
+
+	apiConfig.plans = entitlements.Default()
+	apiConfig.chirpRate = ratelimit.New(time.Hour)
+	apiConfig.urlWeight, err = loadChirpLimits(apiConfig.plans)
+
+	if err != nil {
+		log.Fatal("Invalid chirp limits: ", err)
+	}
+
+	apiConfig.moderation = moderation.NewPipeline(moderation.DefaultChain())
+
+	//rules file is watched and reloaded on change
+	if rulesPath := os.Getenv("MODERATION_RULES"); rulesPath != "" {
+		chain, err := moderation.LoadFile(rulesPath)
+
+		if err != nil {
+			log.Fatal("Failed to load moderation rules: ", err)
+		}
+
+		apiConfig.moderation = moderation.NewPipeline(chain)
+		go apiConfig.moderation.Watch(context.Background(), rulesPath, 5*time.Second)
+	}
+
+	//naive in-memory scan for local development, postgres full-text search otherwise
+	apiConfig.searcher = search.Postgres{DB: dbQueries}
+	if os.Getenv("SEARCH_BACKEND") == "memory" {
+		apiConfig.searcher = search.Memory{Chirps: dbQueries.GetAllChirps}
+	}
+
+	//uploads are kept on the local filesystem
+	apiConfig.blobs, apiConfig.mediaMaxBytes, err = loadMediaConfig()
+
+	if err != nil {
+		log.Fatal("Invalid media config: ", err)
+	}
+
+	//uploads that are never attached to a chirp are purged after a day
+	apiConfig.mediaUnattachedTTL = 24 * time.Hour
+
+	if rawTTL := os.Getenv("MEDIA_UNATTACHED_TTL"); rawTTL != "" {
+		apiConfig.mediaUnattachedTTL, err = time.ParseDuration(rawTTL)
+
+		if err != nil {
+			log.Fatal("Invalid MEDIA_UNATTACHED_TTL: ", err)
+		}
+	}
+
+	go apiConfig.reapUnattachedMedia(context.Background(), time.Hour)
+
+	//link previews are fetched in the background, internal addresses are
+	//only reachable when explicitly allowed for local testing
+	apiConfig.linkPreviews = newLinkPreviewer(&linkpreview.Fetcher{
+		AllowPrivate: os.Getenv("LINK_PREVIEW_ALLOW_PRIVATE") == "true",
+		UserAgent:    "Chirpy-LinkPreview/1.0",
+	}, dbQueries)
+	go apiConfig.linkPreviews.Run(context.Background(), 4)
+
+	//in-process pub/sub for streaming clients, keeps the last 1000 events for resuming
+	apiConfig.broker = stream.NewBroker(1000)
+
+	//lapsed Chirpy Red memberships are downgraded in the background
+	go apiConfig.expireSubscriptions(context.Background(), time.Hour)
+
+	//scheduled chirps are published as they come due
+	go apiConfig.publishScheduledChirps(context.Background(), 30*time.Second)
+
+	//deleted chirps can be restored until they are purged
+	apiConfig.chirpRetention = 30 * 24 * time.Hour
+
+	if rawRetention := os.Getenv("CHIRP_RETENTION"); rawRetention != "" {
+		apiConfig.chirpRetention, err = time.ParseDuration(rawRetention)
+
+		if err != nil {
+			log.Fatal("Invalid CHIRP_RETENTION: ", err)
+		}
+	}
+
+	//expired chirps are hidden right away and deleted in the background,
+	//along with deleted chirps past their retention
+	go apiConfig.reapExpiredChirps(context.Background(), time.Minute)
+
+	//outbound webhooks are sent by a worker polling the delivery queue
+	//endpoints can't be on internal addresses unless allowed for local testing
+	apiConfig.webhooksAllowPrivate = os.Getenv("WEBHOOKS_ALLOW_PRIVATE") == "true"
+	webhookWorker := &webhooks.Worker{Store: webhooks.Postgres{DB: dbQueries}, AllowPrivate: apiConfig.webhooksAllowPrivate}
+	go webhookWorker.Run(context.Background(), 5*time.Second)
 
 	mux := http.NewServeMux()
 	api := http.NewServeMux()
 	admin := http.NewServeMux()
 
-	admin.HandleFunc("GET /metrics", apiConfig.metricsHandler)
-	admin.HandleFunc("POST /reset", apiConfig.resetHandler)
+	//every admin route needs a session token of a user whose role grants perm
+	adminRoute := func(pattern string, perm roles.Permission, handler http.HandlerFunc) {
+		admin.Handle(pattern, apiConfig.middlewarePermission(perm, handler))
+	}
+
+	adminRoute("GET /metrics", roles.ViewMetrics, apiConfig.metricsHandler)
+	adminRoute("GET /metrics/reaper", roles.ViewMetrics, apiConfig.reaperMetricsHandler)
+	//reset wipes every user, staff included, and still only runs in dev
+	adminRoute("POST /reset", roles.ManageRoles, apiConfig.resetHandler)
+	adminRoute("GET /moderation/flags", roles.ReviewContent, apiConfig.chirpFlagsHandler)
+	adminRoute("POST /chirps/{chirpID}/restore", roles.ReviewContent, apiConfig.restoreChirpHandler)
+	adminRoute("GET /reports", roles.ReviewContent, apiConfig.reportsHandler)
+	adminRoute("GET /reports/{reportID}", roles.ReviewContent, apiConfig.getReportHandler)
+	adminRoute("POST /reports/{reportID}/claim", roles.ReviewContent, apiConfig.claimReportHandler)
+	adminRoute("POST /reports/{reportID}/resolve", roles.ReviewContent, apiConfig.resolveReportHandler)
+	adminRoute("POST /reports/{reportID}/dismiss", roles.ReviewContent, apiConfig.dismissReportHandler)
+	adminRoute("GET /moderation/actions", roles.ReviewContent, apiConfig.moderationActionsHandler)
+	adminRoute("POST /users/{userID}/suspend", roles.SuspendUsers, apiConfig.suspendUserHandler)
+	adminRoute("POST /users/{userID}/unsuspend", roles.SuspendUsers, apiConfig.unsuspendUserHandler)
+	adminRoute("GET /users/{userID}/suspensions", roles.SuspendUsers, apiConfig.suspensionsHandler)
+	adminRoute("PUT /users/{userID}/role", roles.ManageRoles, apiConfig.setRoleHandler)
+	adminRoute("GET /users/{userID}/plan", roles.ManageBilling, apiConfig.planHistoryHandler)
+	adminRoute("GET /webhooks/events", roles.ManageBilling, apiConfig.webhookEventsHandler)
+	adminRoute("POST /webhooks/events/{eventID}/replay", roles.ManageBilling, apiConfig.replayWebhookEventHandler)
 	api.HandleFunc("GET /healthz", healthzHandler)
 	api.HandleFunc("POST /users", apiConfig.makeUserHandler)
 	api.HandleFunc("PUT /users", apiConfig.updateUserHandler)
@@ -139,9 +396,69 @@ func main() {
 	api.HandleFunc("POST /revoke", apiConfig.tokenRevokeHandler)
 	api.HandleFunc("POST /chirps", apiConfig.chirpHandler)
 	api.HandleFunc("GET /chirps", apiConfig.allChirpsHandler)
+	api.HandleFunc("GET /chirps/limits", apiConfig.chirpLimitsHandler)
+	api.HandleFunc("GET /chirps/stream", apiConfig.chirpStreamHandler)
+	api.HandleFunc("GET /ws", apiConfig.wsHandler)
 	api.HandleFunc("GET /chirps/{chirpID}", apiConfig.getChirpHandler)
+	api.HandleFunc("PUT /chirps/{chirpID}", apiConfig.editChirpHandler)
 	api.HandleFunc("DELETE /chirps/{chirpID}", apiConfig.deleteChirpHandler)
+	api.HandleFunc("POST /chirps/{chirpID}/like", apiConfig.likeHandler)
+	api.HandleFunc("DELETE /chirps/{chirpID}/like", apiConfig.unlikeHandler)
+	api.HandleFunc("GET /chirps/{chirpID}/poll", apiConfig.getPollHandler)
+	api.HandleFunc("POST /chirps/{chirpID}/poll/votes", apiConfig.votePollHandler)
+	api.HandleFunc("GET /chirps/{chirpID}/replies", apiConfig.repliesHandler)
+	api.HandleFunc("PUT /chirps/{chirpID}/bookmark", apiConfig.bookmarkHandler)
+	api.HandleFunc("DELETE /chirps/{chirpID}/bookmark", apiConfig.removeBookmarkHandler)
+	api.HandleFunc("GET /bookmarks", apiConfig.bookmarksHandler)
+	api.HandleFunc("POST /lists", apiConfig.createListHandler)
+	api.HandleFunc("GET /lists", apiConfig.myListsHandler)
+	api.HandleFunc("GET /lists/{listID}", apiConfig.getListHandler)
+	api.HandleFunc("PUT /lists/{listID}", apiConfig.updateListHandler)
+	api.HandleFunc("DELETE /lists/{listID}", apiConfig.deleteListHandler)
+	api.HandleFunc("GET /lists/{listID}/members", apiConfig.listMembersHandler)
+	api.HandleFunc("PUT /lists/{listID}/members/{userID}", apiConfig.addListMemberHandler)
+	api.HandleFunc("DELETE /lists/{listID}/members/{userID}", apiConfig.removeListMemberHandler)
+	api.HandleFunc("GET /lists/{listID}/chirps", apiConfig.listTimelineHandler)
+	api.HandleFunc("GET /users/{userID}/lists", apiConfig.userListsHandler)
+	api.HandleFunc("POST /media", apiConfig.uploadMediaHandler)
+	api.HandleFunc("GET /media/{mediaID}", apiConfig.getMediaHandler)
+	api.HandleFunc("GET /media/{mediaID}/thumbnail", apiConfig.getMediaThumbnailHandler)
 	api.HandleFunc("POST /polka/webhooks", apiConfig.isChirpRedWebhooksHandler)
+	api.HandleFunc("POST /webhooks", apiConfig.createWebhookHandler)
+	api.HandleFunc("GET /webhooks", apiConfig.listWebhooksHandler)
+	api.HandleFunc("DELETE /webhooks/{endpointID}", apiConfig.deleteWebhookHandler)
+	api.HandleFunc("POST /webhooks/{endpointID}/enable", apiConfig.enableWebhookHandler)
+	api.HandleFunc("GET /webhooks/{endpointID}/deliveries", apiConfig.webhookDeliveriesHandler)
+	api.HandleFunc("GET /users/{handle}", apiConfig.getProfileHandler)
+	api.HandleFunc("GET /users/me/entitlements", apiConfig.entitlementsHandler)
+	api.HandleFunc("PUT /users/me/profile", apiConfig.updateProfileHandler)
+	api.HandleFunc("POST /users/{userID}/follow", apiConfig.followHandler)
+	api.HandleFunc("DELETE /users/{userID}/follow", apiConfig.unfollowHandler)
+	api.HandleFunc("GET /users/{userID}/followers", apiConfig.followersHandler)
+	api.HandleFunc("GET /users/{userID}/following", apiConfig.followingHandler)
+	api.HandleFunc("POST /users/{userID}/block", apiConfig.blockHandler)
+	api.HandleFunc("DELETE /users/{userID}/block", apiConfig.unblockHandler)
+	api.HandleFunc("POST /users/{userID}/mute", apiConfig.muteHandler)
+	api.HandleFunc("DELETE /users/{userID}/mute", apiConfig.unmuteHandler)
+	api.HandleFunc("GET /users/me/blocks", apiConfig.blocksHandler)
+	api.HandleFunc("GET /users/me/mutes", apiConfig.mutesHandler)
+	api.HandleFunc("POST /reports", apiConfig.createReportHandler)
+	api.HandleFunc("GET /users/me/warnings", apiConfig.warningsHandler)
+	api.HandleFunc("GET /timeline", apiConfig.timelineHandler)
+	api.HandleFunc("GET /hashtags/{tag}/chirps", apiConfig.hashtagChirpsHandler)
+	api.HandleFunc("GET /users/me/mentions", apiConfig.mentionsHandler)
+	api.HandleFunc("GET /users/me/notification-preferences", apiConfig.notificationPreferencesHandler)
+	api.HandleFunc("PUT /users/me/notification-preferences", apiConfig.updateNotificationPreferencesHandler)
+	api.HandleFunc("GET /notifications", apiConfig.notificationsHandler)
+	api.HandleFunc("POST /notifications/read", apiConfig.markNotificationsReadHandler)
+	api.HandleFunc("POST /notifications/{notificationID}/read", apiConfig.markNotificationReadHandler)
+	api.HandleFunc("GET /search/chirps", apiConfig.searchChirpsHandler)
+	api.HandleFunc("POST /drafts", apiConfig.createDraftHandler)
+	api.HandleFunc("GET /drafts", apiConfig.listDraftsHandler)
+	api.HandleFunc("GET /drafts/{draftID}", apiConfig.getDraftHandler)
+	api.HandleFunc("PUT /drafts/{draftID}", apiConfig.updateDraftHandler)
+	api.HandleFunc("DELETE /drafts/{draftID}", apiConfig.cancelDraftHandler)
+	api.HandleFunc("POST /drafts/{draftID}/publish", apiConfig.publishDraftHandler)
 
 
 
@@ -243,21 +560,12 @@ func (cfg *apiConfig) chirpHandler (w http.ResponseWriter, r *http.Request){
 
 	err := decoder.Decode(&request)
 
-	if err != nil || len(request.Body) > 140 {
-		statusCode := http.StatusInternalServerError
-
-		errString :=fmt.Sprintf("Error, unable to create user: %v", err)
-
-		if len(request.Body) > 140 {
-			errString = fmt.Sprintf("Chirp is longer than 140 characters: %v", err)
-			statusCode = http.StatusBadRequest
-		}
-
+	if err != nil {
 		res := errResponse{
-			Error: errString,
+			Error: fmt.Sprintf("Error, unable to create chirp: %v", err),
 		}
 
-		err = marshalHelper(w ,res, statusCode)
+		err = marshalHelper(w ,res, http.StatusInternalServerError)
 		if err != nil {
 			fmt.Printf("create chirp: %v", err)
 		}
@@ -282,38 +590,26 @@ func (cfg *apiConfig) chirpHandler (w http.ResponseWriter, r *http.Request){
 		return
 	}
 
-	// ----------- add profanity clean up here if needed/wanted -----------
-	// replaceArr := []string{"kerfuffle", "sharbert", "fornax"}
-	// content := strings.Split(request.Body, " ")
-	// for i := range content {
-	// 	for _, str := range replaceArr {
-	// 		if str == strings.ToLower(content[i]) {
-	// 			content[i] = strings.ToLower(content[i])
-	// 			content[i] = strings.Replace(content[i], str, "****", -1)
-	// 		}
-	// 	}
-	// }
-	// request.Body = strings.Join(content, " ")
+	//limits depend on the user's plan
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
 
-	var curChirp database.Chirp
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed to load user: %v", err)
+		return
+	}
 
-	curChirp, err = cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body: request.Body,
-		UserID: userID,
-	})
+	res, err := cfg.createChirp(r.Context(), user, request, nil)
 
-	if err != nil {
-		log.Printf("Failed to create chirp")
-		w.WriteHeader(http.StatusInternalServerError)
+	if writeChirpError(w, err, "create chirp") {
 		return
 	}
 
-	res := chirpResponse{
-		ID: curChirp.ID,
-		CreatedAt: curChirp.CreatedAt,
-		UpdatedAt: curChirp.UpdatedAt,
-		Body: curChirp.Body,
-		UserID: curChirp.UserID,
+	if err != nil {
+		log.Printf("Failed to create chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	err = marshalHelper(w ,res, http.StatusCreated)
@@ -323,6 +619,276 @@ func (cfg *apiConfig) chirpHandler (w http.ResponseWriter, r *http.Request){
 }
 
 
+// prepareChirp validates a new chirp and resolves everything it references,
+// without storing anything. Chirps that can't be posted get a *chirpError.
+func (cfg *apiConfig) prepareChirp (ctx context.Context, user database.User, request makeChirpParams) (preparedChirp, error) {
+
+	suspension, suspended, err := cfg.activeSuspension(ctx, user.ID)
+
+	if err != nil {
+		return preparedChirp{}, err
+	}
+
+	if suspended {
+		return preparedChirp{}, &chirpError{status: http.StatusForbidden, message: suspensionMessage(suspension)}
+	}
+
+	body := chirptext.Normalize(request.Body)
+
+	plan := cfg.entitlementsFor(user)
+
+	err = cfg.checkChirpLength(plan, body)
+
+	if err != nil {
+		return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	verdict := cfg.moderation.Check(body)
+
+	if verdict.Action == moderation.ActionReject {
+		logRejection(user.ID, verdict)
+
+		return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: "Chirp rejected by moderation"}
+	}
+
+	//masked words are replaced in the stored body
+	prepared := preparedChirp{
+		plan: plan,
+		verdict: verdict,
+		params: database.CreateChirpParams{
+			Body: verdict.Body,
+			UserID: user.ID,
+			Kind: chirpKindChirp,
+		},
+	}
+
+	prepared.params.ExpiresAt, err = chirpExpiry(request.ExpiresIn)
+
+	if err != nil {
+		return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	if request.OriginalChirpID != "" {
+		original, err := cfg.resolveOriginalChirp(ctx, request.OriginalChirpID)
+
+		if err != nil {
+			log.Printf("Failed to resolve original chirp: %v", err)
+			return preparedChirp{}, &chirpError{status: http.StatusNotFound, message: "Original chirp not found"}
+		}
+
+		prepared.params.OriginalChirpID = uuid.NullUUID{UUID: original.ID, Valid: true}
+
+		prepared.params.Kind = chirpKindQuote
+		if strings.TrimSpace(prepared.params.Body) == "" {
+			prepared.params.Kind = chirpKindRechirp
+			prepared.params.Body = ""
+		}
+	}
+
+	if request.InReplyToID != "" {
+		if prepared.params.Kind == chirpKindRechirp {
+			return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: "A reply needs a body"}
+		}
+
+		parentID, err := uuid.Parse(request.InReplyToID)
+
+		if err == nil {
+			prepared.parent, err = cfg.db.GetChirp(ctx, parentID)
+		}
+
+		if err != nil {
+			log.Printf("Failed to resolve replied to chirp: %v", err)
+			return preparedChirp{}, &chirpError{status: http.StatusNotFound, message: "Chirp being replied to not found"}
+		}
+
+		blocked, err := cfg.db.IsBlocked(ctx, database.IsBlockedParams{
+			UserID: user.ID,
+			OtherID: prepared.parent.UserID,
+		})
+
+		if err != nil {
+			return preparedChirp{}, err
+		}
+
+		if blocked {
+			return preparedChirp{}, &chirpError{status: http.StatusForbidden, message: "You can't reply to this user"}
+		}
+
+		prepared.params.InReplyToID = uuid.NullUUID{UUID: prepared.parent.ID, Valid: true}
+	}
+
+	if len(request.MediaIDs) > 0 && prepared.params.Kind == chirpKindRechirp {
+		return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: "A rechirp can't have media"}
+	}
+
+	if len(request.MediaIDs) > plan.MediaPerChirp {
+		return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: fmt.Sprintf("A chirp can have at most %d media", plan.MediaPerChirp)}
+	}
+
+	prepared.mediaIDs, err = cfg.resolveMedia(ctx, user.ID, request.MediaIDs)
+
+	var mediaErr *mediaError
+	if errors.As(err, &mediaErr) {
+		return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: mediaErr.Error()}
+	}
+
+	if err != nil {
+		return preparedChirp{}, err
+	}
+
+	if request.Poll != nil {
+		prepared.pollOptions, err = cfg.preparePoll(prepared, *request.Poll)
+
+		if err != nil {
+			return preparedChirp{}, &chirpError{status: http.StatusBadRequest, message: err.Error()}
+		}
+
+		prepared.pollClosesAt = request.Poll.ClosesAt.UTC()
+	}
+
+	return prepared, nil
+}
+
+
+// createChirp posts a chirp for user. Everything posting a chirp goes through
+// here, so validation, moderation and limits always apply. inTx, if set, runs
+// in the transaction that stores the chirp, with ctx.
+func (cfg *apiConfig) createChirp (ctx context.Context, user database.User, request makeChirpParams, inTx func(ctx context.Context, q *database.Queries, chirp database.Chirp) error) (chirpResponse, error) {
+
+	prepared, err := cfg.prepareChirp(ctx, user, request)
+
+	if err != nil {
+		return chirpResponse{}, err
+	}
+
+	plan := prepared.plan
+
+	if ok, retryAfter := cfg.chirpRate.Allow(user.ID.String(), plan.ChirpsPerHour); !ok {
+		return chirpResponse{}, &chirpError{
+			status: http.StatusTooManyRequests,
+			message: fmt.Sprintf("Chirp limit of %d per hour reached", plan.ChirpsPerHour),
+			retryAfter: retryAfter,
+		}
+	}
+
+	var curChirp database.Chirp
+
+	//attachments are claimed together with the chirp
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		chirp, err := q.CreateChirp(ctx, prepared.params)
+
+		if err != nil {
+			return err
+		}
+
+		for i, mediaID := range prepared.mediaIDs {
+			err = q.AttachMedia(ctx, database.AttachMediaParams{
+				ChirpID: chirp.ID,
+				MediaID: mediaID,
+				Position: int32(i),
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if prepared.pollOptions != nil {
+			err = createPoll(ctx, q, chirp.ID, prepared.pollOptions, prepared.pollClosesAt)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if inTx != nil {
+			err = inTx(ctx, q, chirp)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		curChirp = chirp
+		return nil
+	})
+
+	//a chirp that wasn't stored doesn't count against the limit
+	if err != nil {
+		cfg.chirpRate.Refund(user.ID.String())
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "chirp_attachments_media_id_key" {
+		return chirpResponse{}, &chirpError{status: http.StatusConflict, message: "Media already attached to another chirp"}
+	}
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "chirps_rechirp_once_idx" {
+		return chirpResponse{}, &chirpError{status: http.StatusConflict, message: "Chirp already rechirped"}
+	}
+
+	if err != nil {
+		return chirpResponse{}, err
+	}
+
+	if prepared.verdict.Action == moderation.ActionFlag {
+		cfg.flagChirp(ctx, curChirp, prepared.verdict)
+	}
+
+	cfg.indexChirpEntities(ctx, curChirp)
+
+	if cfg.timelineFanout {
+		err = cfg.db.FanOutChirp(ctx, database.FanOutChirpParams{
+			ChirpID: curChirp.ID,
+			AuthorID: curChirp.UserID,
+			CreatedAt: curChirp.CreatedAt,
+		})
+
+		//the chirp is still created, it is only missing from materialized timelines
+		if err != nil {
+			log.Printf("Failed to fan out chirp: %v", err)
+		}
+	}
+
+	res := cfg.chirpToResponse(ctx, curChirp)
+
+	cfg.emitEvent(ctx, webhooks.ChirpCreated, curChirp.UserID, res)
+
+	//a handle mentioned twice is only notified once, mentioning yourself never is
+	notified := map[uuid.UUID]bool{curChirp.UserID: true}
+
+	//the author being replied to gets the reply, not also a mention
+	if curChirp.InReplyToID.Valid {
+		cfg.notify(ctx, prepared.parent.UserID, curChirp.UserID, notify.TypeReply, curChirp.InReplyToID)
+		notified[prepared.parent.UserID] = true
+	}
+
+	for _, mention := range res.Entities.Mentions {
+		if notified[mention.UserID] {
+			continue
+		}
+		notified[mention.UserID] = true
+
+		//mentions from muted or blocked users aren't pushed, notify skips them too
+		hidden, err := cfg.hiddenUsersFor(ctx, mention.UserID)
+
+		if err != nil {
+			log.Printf("Failed to retreive hidden users of %v: %v", mention.UserID, err)
+			continue
+		}
+
+		if hidden[curChirp.UserID] {
+			continue
+		}
+
+		cfg.publish(realtimeMention, mention.UserID, res)
+		cfg.notify(ctx, mention.UserID, curChirp.UserID, notify.TypeMention, uuid.NullUUID{UUID: curChirp.ID, Valid: true})
+	}
+
+	return res, nil
+}
+
+
 func (cfg *apiConfig) allChirpsHandler (w http.ResponseWriter, r *http.Request) {
 
 	queryParams := r.URL.Query()
@@ -330,6 +896,19 @@ func (cfg *apiConfig) allChirpsHandler (w http.ResponseWriter, r *http.Request)
 	// Returns the first value associated with "author_id"
 	authorId := queryParams.Get("author_id")
 
+	// author=@handle can be used instead of author_id
+	if author := queryParams.Get("author"); authorId == "" && author != "" {
+		authorUser, err := cfg.resolveUserRef(r, author)
+
+		if err != nil {
+			log.Printf("Failed to retreive author %v: %v", author, err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		authorId = authorUser.ID.String()
+	}
+
 	userID, err := uuid.Parse(authorId)
 
 	if err != nil{
@@ -350,6 +929,17 @@ func (cfg *apiConfig) allChirpsHandler (w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	//signed in users don't see chirps of users they muted or are blocked with
+	hidden, err := cfg.hiddenUsersFor(r.Context(), cfg.optionalViewer(r))
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	allChirps = hidden.filter(allChirps)
+
 	var filteredChirps []database.Chirp
 
 	if authorId != "" && err == nil {
@@ -370,17 +960,7 @@ func (cfg *apiConfig) allChirpsHandler (w http.ResponseWriter, r *http.Request)
 			return filteredChirps[i].CreatedAt.After(filteredChirps[j].CreatedAt)
 		})
 
-	var res []chirpResponse;
-
-	for _, chirp := range filteredChirps {
-		res = append(res, chirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
-	}
+	res := cfg.chirpsToResponse(r.Context(), filteredChirps)
 
 	err = marshalHelper(w ,res, http.StatusOK)
 	if err != nil {
@@ -406,13 +986,21 @@ func (cfg *apiConfig) getChirpHandler (w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res := chirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		}
+	hidden, err := cfg.hiddenUsersFor(r.Context(), cfg.optionalViewer(r))
+
+	if err != nil {
+		log.Printf("Failed to retreive hidden users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//chirps of muted or blocked users look like they don't exist
+	if hidden[chirp.UserID] {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	res := cfg.chirpToResponse(r.Context(), chirp)
 
 
 		err = marshalHelper(w ,res, http.StatusOK)
@@ -422,6 +1010,122 @@ func (cfg *apiConfig) getChirpHandler (w http.ResponseWriter, r *http.Request) {
 }
 
 
+// rechirping a rechirp references the chirp it points to, so references stay one level deep
+func (cfg *apiConfig) resolveOriginalChirp (ctx context.Context, rawID string) (database.Chirp, error) {
+
+	originalID, err := uuid.Parse(rawID)
+
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	original, err := cfg.db.GetChirp(ctx, originalID)
+
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if original.Kind != chirpKindRechirp {
+		return original, nil
+	}
+
+	if !original.OriginalChirpID.Valid {
+		return database.Chirp{}, fmt.Errorf("rechirp %v references a deleted chirp", original.ID)
+	}
+
+	return cfg.db.GetChirp(ctx, original.OriginalChirpID.UUID)
+}
+
+
+func (cfg *apiConfig) chirpToResponse (ctx context.Context, chirp database.Chirp) chirpResponse {
+	return cfg.chirpsToResponse(ctx, []database.Chirp{chirp})[0]
+}
+
+
+// chirpsToResponse builds the responses of a list of chirps with a fixed
+// number of queries, however many chirps there are
+func (cfg *apiConfig) chirpsToResponse (ctx context.Context, chirps []database.Chirp) []chirpResponse {
+
+	batch := cfg.loadChirpBatch(ctx, chirps)
+
+	var res []chirpResponse
+
+	for _, chirp := range chirps {
+		res = append(res, batchChirpResponse(batch, chirp))
+	}
+
+	return res
+}
+
+
+// chirp fields shared by a chirp and the original it references
+func baseChirpResponse (batch chirpBatch, chirp database.Chirp) chirpResponse {
+
+	res := chirpResponse{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		Kind:      chirp.Kind,
+		Entities:  chirpEntities(chirp, batch.mentions[chirp.ID]),
+		Media:     batch.media[chirp.ID],
+		LinkPreviews: batch.previews[chirp.ID],
+		Poll:      batch.polls[chirp.ID],
+	}
+
+	if chirp.InReplyToID.Valid {
+		res.InReplyToID = &chirp.InReplyToID.UUID
+	}
+
+	if chirp.ExpiresAt.Valid {
+		res.ExpiresAt = &chirp.ExpiresAt.Time
+	}
+
+	res.LikeCount = batch.counts[chirp.ID].LikeCount
+	res.ReplyCount = batch.counts[chirp.ID].ReplyCount
+
+	return res
+}
+
+
+//batchChirpResponse builds one chirp's response from what loadChirpBatch loaded
+func batchChirpResponse (batch chirpBatch, chirp database.Chirp) chirpResponse {
+
+	//deleted chirps only show up in threads, as tombstones
+	if chirp.DeletedAt.Valid {
+		return chirpTombstone(chirp, batch.counts[chirp.ID])
+	}
+
+	res := baseChirpResponse(batch, chirp)
+
+	if chirp.Kind == chirpKindChirp {
+		return res
+	}
+
+	res.Original = &originalChirpResponse{}
+
+	//original_chirp_id is nulled once the original is purged, until then
+	//the deleted original isn't found
+	if !chirp.OriginalChirpID.Valid {
+		return res
+	}
+
+	original, ok := batch.originals[chirp.OriginalChirpID.UUID]
+
+	if !ok {
+		return res
+	}
+
+	originalRes := baseChirpResponse(batch, original)
+
+	res.Original.Available = true
+	res.Original.chirpResponse = &originalRes
+
+	return res
+}
+
+
 func (cfg *apiConfig) deleteChirpHandler (w http.ResponseWriter, r *http.Request) {
 	//expecting session/JWT token as bearer token
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
@@ -463,7 +1167,7 @@ func (cfg *apiConfig) deleteChirpHandler (w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = cfg.db.DeleteChirp(r.Context(), database.DeleteChirpParams{
+	deleted, err := cfg.db.DeleteChirp(r.Context(), database.DeleteChirpParams{
 		ID: chirp.ID,
 		UserID: userID,
 	})
@@ -474,6 +1178,14 @@ func (cfg *apiConfig) deleteChirpHandler (w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	//deleted by a concurrent request
+	if deleted == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cfg.emitEvent(r.Context(), webhooks.ChirpDeleted, chirp.UserID, chirpDeletedEvent{ID: chirp.ID, UserID: chirp.UserID})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -511,8 +1223,26 @@ func (cfg *apiConfig) makeUserHandler (w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{Email: request.Email, HashedPassword: hashedPass})
+	handle, err := parseHandle(request.Handle)
+
+	if err != nil {
+		err = marshalHelper(w, errResponse{Error: err.Error()}, http.StatusBadRequest)
+		if err != nil {
+			fmt.Printf("create user: %v", err)
+		}
+		return
+	}
+
+	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{Email: request.Email, HashedPassword: hashedPass, Handle: handle})
 
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_handle_lower_idx" {
+		err = marshalHelper(w, errResponse{Error: "Handle is already taken"}, http.StatusConflict)
+		if err != nil {
+			fmt.Printf("create user: %v", err)
+		}
+		return
+	}
 
 	//Fail to add user request, return error message
 	if err != nil {
@@ -536,6 +1266,7 @@ func (cfg *apiConfig) makeUserHandler (w http.ResponseWriter, r *http.Request) {
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		Email: user.Email,
+		Handle: user.Handle.String,
 		IsChirpRed: user.IsChirpRed,
 	}
 
@@ -590,6 +1321,19 @@ func (cfg *apiConfig) loginHandler (w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	suspension, suspended, err := cfg.activeSuspension(r.Context(), user.ID)
+
+	if err != nil {
+		log.Printf("Failed to check suspension: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if suspended {
+		writeSuspended(w, suspension, "user login")
+		return
+	}
+
 	token, err := auth.MakeJWT(user.ID, cfg.secret, time.Hour)
 
 	if err != nil {
@@ -628,7 +1372,9 @@ func (cfg *apiConfig) loginHandler (w http.ResponseWriter, r *http.Request) {
 		Email: user.Email,
 		Token: token,
 		RefreshToken: refreshToken,
+		Handle: user.Handle.String,
 		IsChirpRed: user.IsChirpRed,
+		Role: user.Role,
 	}
 
 
@@ -723,6 +1469,7 @@ func (cfg *apiConfig) updateUserHandler (w http.ResponseWriter, r *http.Request)
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		Email: user.Email,
+		Handle: user.Handle.String,
 		IsChirpRed: user.IsChirpRed,
 	}
 
@@ -737,62 +1484,109 @@ func (cfg *apiConfig) updateUserHandler (w http.ResponseWriter, r *http.Request)
 
 
 func (cfg *apiConfig) isChirpRedWebhooksHandler (w http.ResponseWriter, r *http.Request) {
-	//expects APIKey to be passed in header as Authorization: ApiKey <key>
+	//expects a Webhook-Signature header, or Authorization: ApiKey <key> in compatibility mode
 
-	apiKey, err := auth.GetAPIKey(r.Header)
+	//the body is read before the sender is verified, so it is capped
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPolkaWebhookBytes))
 
-	if err != nil || apiKey != cfg.polkaKey {
-		log.Printf("Rejected request, invalid APIKey")
-
-		w.WriteHeader(http.StatusUnauthorized)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	Decoder := json.NewDecoder(r.Body)
-
-	var request isChirpRedWebhookRequest
-
-	err = Decoder.Decode(&request)
-
 	if err != nil {
-		log.Printf("Failed to decode request: %v", err)
+		log.Printf("Failed to read request: %v", err)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if request.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
+	err = cfg.verifyPolkaRequest(r.Header, payload)
+
+	if err != nil {
+		log.Printf("Rejected webhook: %v", err)
+
+		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	userID, err := uuid.Parse(request.Data.UserID)
+	var request isChirpRedWebhookRequest
+
+	err = json.Unmarshal(payload, &request)
 
+	//retrying a malformed request won't fix it
 	if err != nil {
-		log.Printf("Failed parse user id: %v", err)
+		log.Printf("Failed to decode request: %v", err)
 
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	_, err = cfg.db.UpgradeChirpRed(r.Context(), userID)
+	//every delivery is logged, retries of a logged event get the original answer
+	eventID := polkaEventID(request, payload)
+
+	event, err := cfg.db.CreateWebhookEvent(r.Context(), database.CreateWebhookEventParams{
+		Source: polkaSource,
+		EventID: eventID,
+		EventType: request.Event,
+		Payload: string(payload),
+	})
 
 	if errors.Is(err, sql.ErrNoRows) {
-		log.Printf("no rows found: %v", err)
+		event, err = cfg.db.GetWebhookEventByEventID(r.Context(), database.GetWebhookEventByEventIDParams{
+			Source: polkaSource,
+			EventID: eventID,
+		})
 
-		w.WriteHeader(http.StatusNotFound)
-		return
+		if err != nil {
+			log.Printf("Failed to retreive duplicate webhook event: %v", err)
+
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		//the first delivery is still being processed
+		if event.Status == webhookProcessing && !webhooks.Abandoned(event.Status, event.StartedAt, time.Now(), webhooks.InboundLease) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if event.Status != webhookProcessing {
+			w.WriteHeader(int(event.ResponseStatus))
+			return
+		}
+
+		//the first delivery was abandoned, by a crash or a result that failed to save
+		event, err = cfg.db.ReclaimWebhookEvent(r.Context(), database.ReclaimWebhookEventParams{
+			ID: event.ID,
+			StaleBefore: webhooks.StaleBefore(time.Now(), webhooks.InboundLease),
+		})
+
+		//another retry took it over first
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
 	}
 
 	if err != nil {
-		log.Printf("error upgrading user: %v", err)
+		log.Printf("Failed to log webhook event: %v", err)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	event, err = cfg.processPolkaEvent(r.Context(), event)
 
-	w.WriteHeader(http.StatusNoContent)
+	if err != nil {
+		log.Printf("Failed to record webhook result: %v", err)
+
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(int(event.ResponseStatus))
 }
 
 func (cfg *apiConfig) tokenRefreshHandler (w http.ResponseWriter, r *http.Request) {
@@ -817,6 +1611,20 @@ func (cfg *apiConfig) tokenRefreshHandler (w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	suspension, suspended, err := cfg.activeSuspension(r.Context(), refreshToken.UserID)
+
+	if err != nil {
+		log.Printf("Failed to check suspension: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//a suspension ends sessions as they come up for refresh
+	if suspended {
+		writeSuspended(w, suspension, "token refresh")
+		return
+	}
+
 	newToken, err := auth.MakeJWT(refreshToken.UserID, cfg.secret, time.Hour)
 
 	if err != nil {
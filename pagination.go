@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/JonMunkholm/server/internal/cursor"
+	"github.com/JonMunkholm/server/internal/database"
+)
+
+const defaultPageSize = 20
+const maxPageSize = 100
+
+type chirpPageResponse struct {
+	Chirps     []chirpResponse `json:"chirps"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// parsePage reads the ?cursor= and ?limit= query params shared by all paginated endpoints
+func parsePage(r *http.Request) (cursor.Cursor, int32, error) {
+
+	queryParams := r.URL.Query()
+
+	pos, err := cursor.Decode(queryParams.Get("cursor"))
+
+	if err != nil {
+		return cursor.Cursor{}, 0, err
+	}
+
+	limit := defaultPageSize
+
+	if rawLimit := queryParams.Get("limit"); rawLimit != "" {
+		limit, err = strconv.Atoi(rawLimit)
+
+		if err != nil || limit < 1 {
+			return cursor.Cursor{}, 0, fmt.Errorf("invalid limit: %q", rawLimit)
+		}
+	}
+
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	return pos, int32(limit), nil
+}
+
+// chirpPage builds a page of chirps ordered by (created_at DESC, id DESC), a
+// full page means there may be more so it gets a next cursor. Chirps of
+// hidden authors are left out after the cursor is taken, so a page may come
+// back short without skipping anything.
+func (cfg *apiConfig) chirpPage(ctx context.Context, chirps []database.Chirp, limit int32, hidden hiddenUsers) chirpPageResponse {
+
+	res := chirpPageResponse{
+		Chirps: []chirpResponse{},
+	}
+
+	res.Chirps = append(res.Chirps, cfg.chirpsToResponse(ctx, hidden.filter(chirps))...)
+
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		res.NextCursor = cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return res
+}
@@ -0,0 +1,62 @@
+-- name: BlockUser :execrows
+-- returns 0 when the user was already blocked
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
+
+-- name: GetBlocks :many
+SELECT * FROM blocks
+WHERE blocker_id = sqlc.arg(user_id)::uuid
+AND (created_at, blocked_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: IsBlocked :one
+-- true when either user blocked the other
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_id)::uuid AND blocked_id = sqlc.arg(other_id)::uuid)
+    OR (blocker_id = sqlc.arg(other_id)::uuid AND blocked_id = sqlc.arg(user_id)::uuid)
+);
+
+-- name: MuteUser :execrows
+-- returns 0 when the user was already muted
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2;
+
+-- name: GetMutes :many
+SELECT * FROM mutes
+WHERE muter_id = sqlc.arg(user_id)::uuid
+AND (created_at, muted_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetHiddenUserIDs :many
+-- users whose content is hidden from the user: blocked either way, or muted
+SELECT blocked_id AS user_id FROM blocks
+WHERE blocker_id = $1
+UNION
+SELECT blocker_id FROM blocks
+WHERE blocked_id = $1
+UNION
+SELECT muted_id FROM mutes
+WHERE muter_id = $1;
@@ -0,0 +1,16 @@
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (id, chirp_id, filter, reason, created_at, reviewed_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW(),
+    NULL
+);
+
+-- name: GetUnreviewedChirpFlags :many
+SELECT * FROM chirp_flags
+WHERE reviewed_at IS NULL
+ORDER BY created_at
+LIMIT $1;
@@ -0,0 +1,96 @@
+-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, kind, original_chirp_id, in_reply_to_id, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: ResetChirps :exec
+DELETE FROM chirps;
+
+-- name: GetAllChirps :many
+ SELECT * FROM chirps
+ WHERE deleted_at IS NULL
+ AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetChirp :one
+ SELECT * FROM chirps
+ WHERE chirps.id = $1
+ AND chirps.deleted_at IS NULL
+ AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW());
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: DeleteChirp :execrows
+-- chirps are kept as tombstones until purged, purged once the retention period passes
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $3, updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING *;
+
+-- name: GetChirpReplies :many
+SELECT * FROM chirps
+WHERE in_reply_to_id = sqlc.arg(chirp_id)::uuid
+AND (expires_at IS NULL OR expires_at > NOW())
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetExpiredChirpThreads :many
+-- expired chirps due at now, along with every reply below them
+WITH RECURSIVE doomed AS (
+    SELECT * FROM (
+        SELECT chirps.id, chirps.user_id, true AS expired FROM chirps
+        WHERE chirps.expires_at <= sqlc.arg(now)::timestamp
+        ORDER BY chirps.expires_at
+        LIMIT sqlc.arg(batch_size)::int
+    ) AS expired_chirps
+    UNION
+    SELECT chirps.id, chirps.user_id, false FROM chirps
+    JOIN doomed ON chirps.in_reply_to_id = doomed.id
+)
+SELECT id, user_id, expired FROM doomed;
+
+-- name: DeleteChirps :execrows
+DELETE FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetChirpWithDeleted :one
+-- also returns deleted chirps, for tombstones and restoring
+SELECT * FROM chirps
+WHERE id = $1;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1
+AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: GetPurgeableChirps :many
+SELECT id FROM chirps
+WHERE deleted_at < sqlc.arg(deleted_before)::timestamp
+ORDER BY deleted_at
+LIMIT sqlc.arg(batch_size)::int;
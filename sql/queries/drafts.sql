@@ -0,0 +1,80 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, user_id, content, status, publish_at, locked_until, error, chirp_id, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NULL,
+    '',
+    NULL,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id = $1
+AND user_id = $2;
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id = sqlc.arg(user_id)::uuid
+AND status = ANY(sqlc.arg(statuses)::text[])
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: UpdateDraft :one
+-- published and canceled drafts, and drafts being published, can't be changed
+UPDATE drafts
+SET content = $3, status = $4, publish_at = $5, error = '', updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND status IN ('draft', 'scheduled', 'failed')
+AND (locked_until IS NULL OR locked_until < NOW())
+RETURNING *;
+
+-- name: CancelDraft :one
+UPDATE drafts
+SET status = 'canceled', updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND status IN ('draft', 'scheduled', 'failed')
+AND (locked_until IS NULL OR locked_until < NOW())
+RETURNING *;
+
+-- name: ClaimDueDrafts :many
+-- hands out due drafts, hidden from other claims until locked_until
+UPDATE drafts
+SET locked_until = sqlc.arg(locked_until)::timestamp
+WHERE id IN (
+    SELECT id FROM drafts
+    WHERE status = 'scheduled'
+    AND publish_at <= sqlc.arg(now)::timestamp
+    AND (locked_until IS NULL OR locked_until <= sqlc.arg(now)::timestamp)
+    ORDER BY publish_at
+    LIMIT sqlc.arg(batch_size)::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkDraftPublished :execrows
+-- returns 0 when the draft was canceled or already published
+UPDATE drafts
+SET status = 'published', chirp_id = $2, error = '', locked_until = NULL, updated_at = NOW()
+WHERE id = $1
+AND status IN ('draft', 'scheduled', 'failed');
+
+-- name: FailDraft :exec
+UPDATE drafts
+SET status = 'failed', error = $2, locked_until = NULL, updated_at = NOW()
+WHERE id = $1
+AND status = 'scheduled';
+
+-- name: DelayDraft :exec
+UPDATE drafts
+SET locked_until = $2
+WHERE id = $1;
@@ -0,0 +1,44 @@
+-- name: AddChirpMentions :exec
+-- handles that don't belong to a user are skipped
+INSERT INTO chirp_mentions (chirp_id, user_id, created_at)
+SELECT sqlc.arg(chirp_id)::uuid, users.id, sqlc.arg(created_at)::timestamp
+FROM users
+WHERE LOWER(users.handle) = ANY(sqlc.arg(handles)::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT sqlc.arg(chirp_id)::uuid, UNNEST(sqlc.arg(tags)::text[]), sqlc.arg(created_at)::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpEntities :exec
+-- clears the index before an edited chirp is re-indexed
+WITH deleted_mentions AS (
+    DELETE FROM chirp_mentions WHERE chirp_mentions.chirp_id = $1
+)
+DELETE FROM chirp_hashtags WHERE chirp_hashtags.chirp_id = $1;
+
+-- name: GetChirpsMentions :many
+SELECT chirp_mentions.chirp_id, users.id, users.handle FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpsByHashtag :many
+SELECT chirps.* FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.tag = sqlc.arg(tag)::text
+AND (chirp_hashtags.created_at, chirp_hashtags.chirp_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+ORDER BY chirp_hashtags.created_at DESC, chirp_hashtags.chirp_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetMentions :many
+SELECT chirps.* FROM chirp_mentions
+JOIN chirps ON chirps.id = chirp_mentions.chirp_id
+WHERE chirp_mentions.user_id = sqlc.arg(user_id)::uuid
+AND (chirp_mentions.created_at, chirp_mentions.chirp_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+ORDER BY chirp_mentions.created_at DESC, chirp_mentions.chirp_id DESC
+LIMIT sqlc.arg(page_size)::int;
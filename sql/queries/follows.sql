@@ -0,0 +1,32 @@
+-- name: FollowUser :execrows
+-- returns 0 when the user was already followed
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: GetFollowers :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg(user_id)::uuid
+AND (created_at, follower_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetFollowing :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg(user_id)::uuid
+AND (created_at, followee_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1;
@@ -0,0 +1,22 @@
+-- name: LikeChirp :execrows
+-- returns 0 when the chirp was already liked
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE chirp_id = $1
+AND user_id = $2;
+
+-- name: GetChirpsCounts :many
+SELECT
+    chirps.id AS chirp_id,
+    (SELECT COUNT(*) FROM chirp_likes WHERE chirp_likes.chirp_id = chirps.id)::bigint AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.in_reply_to_id = chirps.id AND replies.deleted_at IS NULL AND (replies.expires_at IS NULL OR replies.expires_at > NOW()))::bigint AS reply_count
+FROM chirps
+WHERE chirps.id = ANY(sqlc.arg(chirp_ids)::uuid[]);
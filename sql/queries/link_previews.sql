@@ -0,0 +1,24 @@
+-- name: SaveLinkPreview :exec
+INSERT INTO link_previews (url, status, title, description, image_url, site_name, error, fetched_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    NOW()
+)
+ON CONFLICT (url) DO UPDATE
+SET status = EXCLUDED.status,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    image_url = EXCLUDED.image_url,
+    site_name = EXCLUDED.site_name,
+    error = EXCLUDED.error,
+    fetched_at = EXCLUDED.fetched_at;
+
+-- name: GetLinkPreviews :many
+SELECT * FROM link_previews
+WHERE url = ANY(sqlc.arg(urls)::text[]);
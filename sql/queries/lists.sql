@@ -0,0 +1,92 @@
+-- name: CreateList :one
+INSERT INTO lists (id, owner_id, name, description, private, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: LockList :one
+-- held until the transaction ends, so members are added one at a time
+SELECT id FROM lists
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetUserLists :many
+-- private lists are only included for their owner
+SELECT * FROM lists
+WHERE owner_id = sqlc.arg(owner_id)::uuid
+AND (sqlc.arg(include_private)::boolean OR NOT private)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: CountUserLists :one
+SELECT COUNT(*) FROM lists
+WHERE owner_id = $1;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $3, description = $4, private = $5, updated_at = NOW()
+WHERE id = $1
+AND owner_id = $2
+RETURNING *;
+
+-- name: DeleteList :execrows
+DELETE FROM lists
+WHERE id = $1
+AND owner_id = $2;
+
+-- name: AddListMember :execrows
+-- returns 0 when the user was already a member
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :exec
+DELETE FROM list_members
+WHERE list_id = $1
+AND user_id = $2;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+-- name: GetListMembers :many
+SELECT * FROM list_members
+WHERE list_id = sqlc.arg(list_id)::uuid
+AND (created_at, user_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, user_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetListTimeline :many
+-- same approach as GetTimeline, with the list's members as the authors
+SELECT chirps.* FROM (
+    SELECT list_members.user_id AS author_id FROM list_members
+    WHERE list_members.list_id = sqlc.arg(list_id)::uuid
+) AS authors
+CROSS JOIN LATERAL (
+    SELECT recent.id FROM chirps AS recent
+    WHERE recent.user_id = authors.author_id
+    AND recent.deleted_at IS NULL
+    AND (recent.expires_at IS NULL OR recent.expires_at > NOW())
+    AND (recent.created_at, recent.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+    ORDER BY recent.created_at DESC, recent.id DESC
+    LIMIT sqlc.arg(page_size)::int
+) AS latest
+JOIN chirps ON chirps.id = latest.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size)::int;
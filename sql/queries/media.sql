@@ -0,0 +1,70 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, user_id, content_type, size_bytes, width, height, blob_key, thumbnail_key, thumbnail_type, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetMediaFile :one
+SELECT * FROM media_files
+WHERE id = $1;
+
+-- name: GetUnattachedMediaFile :one
+-- media can only be attached by its uploader, and only once
+SELECT media_files.* FROM media_files
+LEFT JOIN chirp_attachments ON chirp_attachments.media_id = media_files.id
+WHERE media_files.id = $1
+AND media_files.user_id = $2
+AND chirp_attachments.media_id IS NULL;
+
+-- name: AttachMedia :exec
+INSERT INTO chirp_attachments (chirp_id, media_id, position)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: GetChirpsMedia :many
+SELECT chirp_attachments.chirp_id, sqlc.embed(media_files) FROM media_files
+JOIN chirp_attachments ON chirp_attachments.media_id = media_files.id
+WHERE chirp_attachments.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_attachments.chirp_id, chirp_attachments.position;
+
+-- name: GetChirpsMediaFiles :many
+SELECT media_files.* FROM media_files
+JOIN chirp_attachments ON chirp_attachments.media_id = media_files.id
+WHERE chirp_attachments.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: DeleteMediaFiles :exec
+DELETE FROM media_files
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: CountUnattachedMedia :one
+SELECT COUNT(*) FROM media_files
+WHERE user_id = $1
+AND NOT EXISTS (SELECT 1 FROM chirp_attachments WHERE chirp_attachments.media_id = media_files.id);
+
+-- name: GetAbandonedMediaFiles :many
+-- uploads never attached to a chirp, and not waiting in a draft either
+SELECT * FROM media_files
+WHERE created_at < sqlc.arg(uploaded_before)::timestamp
+AND NOT EXISTS (SELECT 1 FROM chirp_attachments WHERE chirp_attachments.media_id = media_files.id)
+AND NOT EXISTS (
+    SELECT 1 FROM drafts
+    WHERE drafts.user_id = media_files.user_id
+    AND drafts.status IN ('draft', 'scheduled', 'failed')
+    AND drafts.content->'media_ids' @> to_jsonb(media_files.id::text)
+)
+ORDER BY created_at
+LIMIT sqlc.arg(batch_size)::int
+FOR UPDATE SKIP LOCKED;
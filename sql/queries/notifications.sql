@@ -0,0 +1,98 @@
+-- name: UpsertNotification :one
+-- joins the user's unread notification with the same group key, if there is
+-- one, leaving it as it is until an actor is added
+INSERT INTO notifications (id, user_id, type, group_key, chirp_id, latest_actor_id, actor_count, read_at, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    0,
+    NULL,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET group_key = EXCLUDED.group_key
+RETURNING *;
+
+-- name: AddNotificationActor :execrows
+-- returns 0 when the actor was already part of the notification
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RefreshNotificationActorCount :one
+-- counts the actors again after one was added, and moves the notification
+-- to the top
+UPDATE notifications
+SET actor_count = (SELECT COUNT(*) FROM notification_actors WHERE notification_actors.notification_id = notifications.id),
+    latest_actor_id = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetNotificationsActorIDs :many
+-- the latest PerNotification actors of each notification
+SELECT ranked.notification_id, ranked.actor_id FROM (
+    SELECT notification_id, actor_id, created_at,
+        ROW_NUMBER() OVER (PARTITION BY notification_id ORDER BY created_at DESC, actor_id) AS position
+    FROM notification_actors
+    WHERE notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+) AS ranked
+WHERE ranked.position <= sqlc.arg(per_notification)::int
+ORDER BY ranked.notification_id, ranked.created_at DESC, ranked.actor_id;
+
+-- name: GetNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)::uuid
+AND (NOT sqlc.arg(unread_only)::bool OR read_at IS NULL)
+AND (updated_at, id) < (sqlc.arg(before_updated_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id)::uuid
+AND id = ANY(sqlc.arg(ids)::uuid[])
+AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :one
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (user_id, replies, likes, mentions, follows, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET replies = EXCLUDED.replies,
+    likes = EXCLUDED.likes,
+    mentions = EXCLUDED.mentions,
+    follows = EXCLUDED.follows,
+    updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,55 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, closes_at, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, text)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPollResults :many
+SELECT poll_options.position, poll_options.text, COUNT(poll_votes.user_id)::bigint AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.chirp_id = poll_options.chirp_id
+AND poll_votes.position = poll_options.position
+WHERE poll_options.chirp_id = $1
+GROUP BY poll_options.position, poll_options.text
+ORDER BY poll_options.position;
+
+-- name: GetPolls :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollsResults :many
+SELECT poll_options.chirp_id, poll_options.position, poll_options.text, COUNT(poll_votes.user_id)::bigint AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.chirp_id = poll_options.chirp_id
+AND poll_votes.position = poll_options.position
+WHERE poll_options.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY poll_options.chirp_id, poll_options.position, poll_options.text
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: VotePoll :execrows
+-- returns 0 when the user already voted or the poll has closed
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+SELECT polls.chirp_id, sqlc.arg(user_id)::uuid, sqlc.arg(position)::int, NOW()
+FROM polls
+WHERE polls.chirp_id = sqlc.arg(chirp_id)::uuid
+AND polls.closes_at > NOW()
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: GetPollVote :one
+SELECT position FROM poll_votes
+WHERE chirp_id = $1
+AND user_id = $2;
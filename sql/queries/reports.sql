@@ -0,0 +1,68 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, reporter_id, user_id, chirp_id, category, details, status, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    'open',
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReportForUpdate :one
+-- locks the report so two moderators can't act on it at once
+SELECT * FROM reports
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListReports :many
+SELECT * FROM reports
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: ClaimReport :one
+UPDATE reports
+SET status = 'claimed', claimed_by = $2, claimed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CloseReport :one
+UPDATE reports
+SET status = $2, resolution = $3, note = $4, closed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, actor_id, action, report_id, user_id, chirp_id, note, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+);
+
+-- name: GetReportActions :many
+SELECT * FROM moderation_actions
+WHERE report_id = $1
+ORDER BY created_at, id;
+
+-- name: ListModerationActions :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
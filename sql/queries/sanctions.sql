@@ -0,0 +1,56 @@
+-- name: CreateSuspension :one
+INSERT INTO suspensions (id, user_id, reason, report_id, created_by, created_at, expires_at, lifted_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    $5,
+    NULL
+)
+RETURNING *;
+
+-- name: GetActiveSuspension :one
+-- the suspension that lasts longest when there are several
+SELECT * FROM suspensions
+WHERE user_id = $1
+AND lifted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY expires_at DESC NULLS FIRST
+LIMIT 1;
+
+-- name: CreateWarning :one
+INSERT INTO warnings (id, user_id, reason, report_id, created_by, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetWarnings :many
+SELECT * FROM warnings
+WHERE user_id = sqlc.arg(user_id)::uuid
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: LiftSuspensions :execrows
+-- returns 0 when the user wasn't suspended
+UPDATE suspensions
+SET lifted_at = NOW(), lifted_by = $2, lift_reason = $3
+WHERE user_id = $1
+AND lifted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetSuspensions :many
+SELECT * FROM suspensions
+WHERE user_id = sqlc.arg(user_id)::uuid
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
@@ -0,0 +1,24 @@
+-- name: SearchChirps :many
+-- websearch_to_tsquery understands "quoted phrases", OR and -excluded terms.
+-- The body is html escaped before ts_headline wraps matches in <mark> tags.
+SELECT sqlc.embed(chirps),
+    ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+    ts_headline(
+        'english',
+        REPLACE(REPLACE(REPLACE(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15'
+    )::text AS snippet
+FROM chirps, websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+WHERE to_tsvector('english', chirps.body) @@ query
+AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until)::timestamp)
+ORDER BY
+    CASE WHEN sqlc.arg(by_relevance)::boolean THEN ts_rank(to_tsvector('english', chirps.body), query) END DESC,
+    chirps.created_at DESC,
+    chirps.id DESC
+LIMIT sqlc.arg(page_size)::int
+OFFSET sqlc.arg(page_offset)::int;
@@ -0,0 +1,76 @@
+-- name: UpsertSubscription :one
+-- starts or renews a subscription, clearing any cancellation
+INSERT INTO subscriptions (user_id, plan, status, current_period_start, current_period_end, canceled_at, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    'active',
+    $3,
+    $4,
+    NULL,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = 'active',
+    current_period_start = EXCLUDED.current_period_start,
+    current_period_end = EXCLUDED.current_period_end,
+    canceled_at = NULL,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: CancelSubscription :one
+-- the membership runs until the end of the paid period
+UPDATE subscriptions
+SET status = 'canceled', canceled_at = NOW(), updated_at = NOW()
+WHERE user_id = $1
+AND status = 'active'
+RETURNING *;
+
+-- name: EndSubscription :exec
+UPDATE subscriptions
+SET status = 'expired', current_period_end = LEAST(current_period_end, NOW()), updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: ExpireSubscriptions :many
+-- downgrades every membership whose period ended before the given time
+-- and records it in the plan history, returns the downgraded users
+WITH expired AS (
+    UPDATE subscriptions
+    SET status = 'expired', updated_at = NOW()
+    WHERE subscriptions.status <> 'expired'
+    AND subscriptions.current_period_end < sqlc.arg(before)::timestamp
+    RETURNING subscriptions.user_id, subscriptions.plan, subscriptions.current_period_end
+), downgraded AS (
+    UPDATE users
+    SET is_chirp_red = FALSE, updated_at = NOW()
+    FROM expired
+    WHERE users.id = expired.user_id
+    RETURNING users.id, expired.plan, expired.current_period_end
+)
+INSERT INTO plan_changes (id, user_id, event, from_plan, to_plan, period_end, created_at)
+SELECT gen_random_uuid(), downgraded.id, 'expired', downgraded.plan, 'free', downgraded.current_period_end, NOW()
+FROM downgraded
+RETURNING plan_changes.user_id;
+
+-- name: CreatePlanChange :exec
+INSERT INTO plan_changes (id, user_id, event, from_plan, to_plan, period_end, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+);
+
+-- name: GetPlanChanges :many
+SELECT * FROM plan_changes
+WHERE user_id = $1
+ORDER BY created_at DESC;
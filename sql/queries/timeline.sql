@@ -0,0 +1,57 @@
+-- name: GetTimeline :many
+-- pulls the newest chirps of each followed author (and the user) from
+-- chirps_user_id_created_at_idx, so the cost is bounded by limit per author
+-- rather than by the total number of chirps those authors have written
+SELECT chirps.* FROM (
+    SELECT follows.followee_id AS author_id FROM follows
+    WHERE follows.follower_id = sqlc.arg(user_id)::uuid
+    UNION ALL
+    SELECT sqlc.arg(user_id)::uuid
+) AS authors
+CROSS JOIN LATERAL (
+    SELECT recent.id FROM chirps AS recent
+    WHERE recent.user_id = authors.author_id
+    AND recent.deleted_at IS NULL
+    AND (recent.expires_at IS NULL OR recent.expires_at > NOW())
+    AND (recent.created_at, recent.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+    ORDER BY recent.created_at DESC, recent.id DESC
+    LIMIT sqlc.arg(page_size)::int
+) AS latest
+JOIN chirps ON chirps.id = latest.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetMaterializedTimeline :many
+SELECT chirps.* FROM timeline_entries
+JOIN chirps ON chirps.id = timeline_entries.chirp_id
+WHERE timeline_entries.user_id = sqlc.arg(user_id)::uuid
+AND (timeline_entries.created_at, timeline_entries.chirp_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, sqlc.arg(chirp_id)::uuid, sqlc.arg(author_id)::uuid, sqlc.arg(created_at)::timestamp
+FROM follows
+WHERE follows.followee_id = sqlc.arg(author_id)::uuid
+UNION ALL
+SELECT sqlc.arg(author_id)::uuid, sqlc.arg(chirp_id)::uuid, sqlc.arg(author_id)::uuid, sqlc.arg(created_at)::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: BackfillTimeline :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT sqlc.arg(user_id)::uuid, chirps.id, chirps.user_id, chirps.created_at
+FROM chirps
+WHERE chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND chirps.user_id = sqlc.arg(author_id)::uuid
+ORDER BY chirps.created_at DESC
+LIMIT 200
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveAuthorFromTimeline :exec
+DELETE FROM timeline_entries
+WHERE user_id = $1
+AND author_id = $2;
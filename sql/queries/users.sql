@@ -0,0 +1,65 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirp_red, handle)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    FALSE,
+    $3
+)
+RETURNING *;
+
+-- name: ResetUsers :exec
+DELETE FROM users;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE users.email = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE users.id = $1;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE users.id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(users.handle) = LOWER($1);
+
+-- name: UpdateUser :exec
+UPDATE users
+SET email = $2, hashed_password = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, profile_updated_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpgradeChirpRed :one
+UPDATE users
+SET is_chirp_red = TRUE, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DowngradeChirpRed :one
+UPDATE users
+SET is_chirp_red = FALSE, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRoleByEmail :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE email = $1;
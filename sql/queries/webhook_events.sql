@@ -0,0 +1,60 @@
+-- name: CreateWebhookEvent :one
+-- returns no rows when the event was already received
+INSERT INTO webhook_events (id, source, event_id, event_type, payload, status, response_status, error, attempts, received_at, processed_at, started_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    'processing',
+    0,
+    '',
+    0,
+    NOW(),
+    NULL,
+    NOW()
+)
+ON CONFLICT (source, event_id) DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: GetWebhookEventByEventID :one
+SELECT * FROM webhook_events
+WHERE source = $1
+AND event_id = $2;
+
+-- name: StartWebhookEventReplay :one
+-- claims a failed event, or one abandoned while processing since before
+-- StaleBefore, so two replays can't run at once
+UPDATE webhook_events
+SET status = 'processing', started_at = NOW()
+WHERE id = $1
+AND (status = 'failed' OR (status = 'processing' AND started_at <= sqlc.arg(stale_before)::timestamp))
+RETURNING *;
+
+-- name: ReclaimWebhookEvent :one
+-- takes over an event abandoned while processing since before StaleBefore,
+-- returns no rows when it is still being processed or someone else took it
+UPDATE webhook_events
+SET started_at = NOW()
+WHERE id = $1
+AND status = 'processing'
+AND started_at <= sqlc.arg(stale_before)::timestamp
+RETURNING *;
+
+-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $2, response_status = $3, error = $4, attempts = attempts + 1, processed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+AND (received_at, id) < (sqlc.arg(before_received_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
@@ -0,0 +1,121 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, user_id, url, secret, events, enabled, consecutive_failures, disabled_at, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    TRUE,
+    0,
+    NULL,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1
+AND user_id = $2;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1
+AND user_id = $2;
+
+-- name: EnableWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET enabled = TRUE, consecutive_failures = 0, disabled_at = NULL, updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+-- name: ResetWebhookEndpointFailures :exec
+UPDATE webhook_endpoints
+SET consecutive_failures = 0
+WHERE id = $1
+AND consecutive_failures > 0;
+
+-- name: RecordWebhookEndpointFailure :one
+-- disables the endpoint once it has failed DisableAfter attempts in a row,
+-- returns whether it is still enabled
+UPDATE webhook_endpoints
+SET consecutive_failures = consecutive_failures + 1,
+    enabled = enabled AND consecutive_failures + 1 < $2::int,
+    disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= $2::int THEN NOW() ELSE disabled_at END,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING enabled;
+
+-- name: EnqueueWebhookDeliveries :exec
+-- queues an event for every enabled endpoint subscribed to its type, only
+-- the endpoints of OwnerID when it is set
+INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, completed_at)
+SELECT gen_random_uuid(), webhook_endpoints.id, $1::uuid, $2::text, $3::text, 'pending', 0, NOW(), NOW(), NULL
+FROM webhook_endpoints
+WHERE webhook_endpoints.enabled
+AND $2::text = ANY(webhook_endpoints.events)
+AND ($4::uuid IS NULL OR webhook_endpoints.user_id = $4::uuid);
+
+-- name: ClaimWebhookDeliveries :many
+-- claims due deliveries until LeaseUntil, so several workers never send the
+-- same delivery at once and a crashed worker's deliveries are picked up again
+WITH due AS (
+    SELECT webhook_deliveries.id FROM webhook_deliveries
+    JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id
+    WHERE webhook_deliveries.status = 'pending'
+    AND webhook_deliveries.next_attempt_at <= $1::timestamp
+    AND webhook_endpoints.enabled
+    ORDER BY webhook_deliveries.next_attempt_at
+    LIMIT $3::int
+    FOR UPDATE OF webhook_deliveries SKIP LOCKED
+), claimed AS (
+    UPDATE webhook_deliveries
+    SET next_attempt_at = $2::timestamp
+    FROM due
+    WHERE webhook_deliveries.id = due.id
+    RETURNING webhook_deliveries.id, webhook_deliveries.endpoint_id, webhook_deliveries.event_id, webhook_deliveries.event_type, webhook_deliveries.payload, webhook_deliveries.attempts
+)
+SELECT claimed.id, claimed.endpoint_id, claimed.event_id, claimed.event_type, claimed.payload, claimed.attempts, webhook_endpoints.url, webhook_endpoints.secret
+FROM claimed
+JOIN webhook_endpoints ON webhook_endpoints.id = claimed.endpoint_id;
+
+-- name: RecordWebhookAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, delivery_id, attempt, status_code, error, response_body, duration_ms, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+);
+
+-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, completed_at = NOW()
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id = $1;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = ANY($1::uuid[])
+ORDER BY delivery_id, attempt;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN kind TEXT NOT NULL DEFAULT 'chirp'
+    CHECK (kind IN ('chirp', 'rechirp', 'quote')),
+ADD COLUMN original_chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_original_chirp_id_idx ON chirps (original_chirp_id);
+
+-- a user can only rechirp the same chirp once
+CREATE UNIQUE INDEX chirps_rechirp_once_idx ON chirps (user_id, original_chirp_id)
+WHERE kind = 'rechirp';
+
+-- +goose Down
+DROP INDEX chirps_rechirp_once_idx;
+DROP INDEX chirps_original_chirp_id_idx;
+ALTER TABLE chirps
+DROP COLUMN original_chirp_id,
+DROP COLUMN kind;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT CHECK (handle ~ '^[A-Za-z0-9_]{3,15}$'),
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN profile_updated_at TIMESTAMP;
+
+-- handles are unique regardless of case, @Chirpy and @chirpy are the same user
+CREATE UNIQUE INDEX users_handle_lower_idx ON users (LOWER(handle));
+
+-- +goose Down
+DROP INDEX users_handle_lower_idx;
+ALTER TABLE users
+DROP COLUMN profile_updated_at,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
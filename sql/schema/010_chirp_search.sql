@@ -0,0 +1,6 @@
+-- +goose Up
+-- must match the to_tsvector expression used by SearchChirps for the index to be used
+CREATE INDEX chirps_body_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_body_search_idx;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- chirps the moderation pipeline let through but wants a person to look at
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    filter TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    reviewed_at TIMESTAMP
+);
+
+CREATE INDEX chirp_flags_unreviewed_idx ON chirp_flags (created_at) WHERE reviewed_at IS NULL;
+
+-- moderators review flagged chirps, admins can do anything on /admin
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN role;
+DROP TABLE chirp_flags;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- one row per user that has ever subscribed through Polka
+CREATE TABLE subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('active', 'canceled', 'expired')),
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    canceled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscriptions_period_end_idx ON subscriptions (current_period_end) WHERE status <> 'expired';
+
+-- every change to a user's membership, for support
+CREATE TABLE plan_changes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    from_plan TEXT NOT NULL,
+    to_plan TEXT NOT NULL,
+    period_end TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX plan_changes_user_id_created_at_idx ON plan_changes (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE plan_changes;
+DROP TABLE subscriptions;
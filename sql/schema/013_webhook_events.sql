@@ -0,0 +1,24 @@
+-- +goose Up
+-- every inbound webhook delivery, so retries are answered from the log
+CREATE TABLE webhook_events (
+    id UUID PRIMARY KEY,
+    source TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('processing', 'processed', 'ignored', 'failed')),
+    response_status INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    received_at TIMESTAMP NOT NULL,
+    processed_at TIMESTAMP,
+    -- when processing last started, events left processing past their
+    -- lease are taken over by the next retry or replay
+    started_at TIMESTAMP NOT NULL,
+    UNIQUE (source, event_id)
+);
+
+CREATE INDEX webhook_events_received_at_idx ON webhook_events (received_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE webhook_events;
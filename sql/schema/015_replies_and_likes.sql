@@ -0,0 +1,19 @@
+-- +goose Up
+-- a reply stays up when the chirp it answers is deleted
+ALTER TABLE chirps ADD COLUMN in_reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_in_reply_to_id_idx ON chirps (in_reply_to_id, created_at DESC, id DESC) WHERE in_reply_to_id IS NOT NULL;
+
+CREATE TABLE chirp_likes (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_likes_user_id_idx ON chirp_likes (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE chirp_likes;
+DROP INDEX chirps_in_reply_to_id_idx;
+ALTER TABLE chirps DROP COLUMN in_reply_to_id;
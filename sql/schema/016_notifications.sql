@@ -0,0 +1,42 @@
+-- +goose Up
+-- one inbox entry per group of unread notifications, a like on a chirp whose
+-- likes are already unread joins that entry instead of adding a row
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('reply', 'like', 'mention', 'follow')),
+    group_key TEXT NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    latest_actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_count INTEGER NOT NULL DEFAULT 0,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- read groups are closed, the next event starts a new one
+CREATE UNIQUE INDEX notifications_unread_group_idx ON notifications (user_id, group_key) WHERE read_at IS NULL;
+CREATE INDEX notifications_user_id_updated_at_idx ON notifications (user_id, updated_at DESC, id DESC);
+
+-- who is behind each grouped notification, an actor is counted once
+CREATE TABLE notification_actors (
+    notification_id UUID NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (notification_id, actor_id)
+);
+
+-- users without a row get every notification
+CREATE TABLE notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    replies BOOLEAN NOT NULL,
+    likes BOOLEAN NOT NULL,
+    mentions BOOLEAN NOT NULL,
+    follows BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notification_actors;
+DROP TABLE notifications;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- uploaded images, the files themselves live in blob storage
+CREATE TABLE media_files (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    blob_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    thumbnail_type TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- the reaper looks for old uploads that were never attached
+CREATE INDEX media_files_created_at_idx ON media_files (created_at);
+
+-- a file is attached to at most one chirp
+CREATE TABLE chirp_attachments (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL UNIQUE REFERENCES media_files(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, media_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
+DROP TABLE media_files;
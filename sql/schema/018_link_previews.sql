@@ -0,0 +1,16 @@
+-- +goose Up
+-- metadata of links in chirps, keyed by the normalized url and shared by
+-- every chirp linking to it
+CREATE TABLE link_previews (
+    url TEXT PRIMARY KEY,
+    status TEXT NOT NULL CHECK (status IN ('ok', 'failed')),
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    image_url TEXT NOT NULL,
+    site_name TEXT NOT NULL,
+    error TEXT NOT NULL,
+    fetched_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE link_previews;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- chirps that aren't published yet. content is the request that will be
+-- posted, it is only validated when the draft is scheduled or published.
+CREATE TABLE drafts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content JSONB NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('draft', 'scheduled', 'published', 'failed', 'canceled')),
+    publish_at TIMESTAMP,
+    -- the scheduler holds a draft while publishing it
+    locked_until TIMESTAMP,
+    error TEXT NOT NULL DEFAULT '',
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CHECK (status <> 'scheduled' OR publish_at IS NOT NULL)
+);
+
+CREATE INDEX drafts_user_id_created_at_idx ON drafts (user_id, created_at DESC, id DESC);
+CREATE INDEX drafts_due_idx ON drafts (publish_at) WHERE status = 'scheduled';
+
+-- +goose Down
+DROP TABLE drafts;
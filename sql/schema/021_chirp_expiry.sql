@@ -0,0 +1,9 @@
+-- +goose Up
+-- chirps with an expires_at are hidden once it passes and deleted by the reaper
+ALTER TABLE chirps ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX chirps_expires_at_idx ON chirps (expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_expires_at_idx;
+ALTER TABLE chirps DROP COLUMN expires_at;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- a block hides both users from each other, a mute only hides the muted
+-- user from the muter
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+CREATE INDEX blocks_blocker_id_created_at_idx ON blocks (blocker_id, created_at DESC, blocked_id DESC);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    CHECK (muter_id <> muted_id)
+);
+
+CREATE INDEX mutes_muter_id_created_at_idx ON mutes (muter_id, created_at DESC, muted_id DESC);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,74 @@
+-- +goose Up
+-- reports of chirps or accounts, worked through by moderators in
+-- /admin/reports. A chirp report is also about its author.
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    category TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolution TEXT,
+    note TEXT NOT NULL DEFAULT '',
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX reports_created_at_idx ON reports (created_at DESC, id DESC);
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at DESC, id DESC);
+
+-- a user reports the same thing once while it is being looked at
+CREATE UNIQUE INDEX reports_pending_idx ON reports (reporter_id, user_id, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'))
+WHERE status IN ('open', 'claimed');
+
+-- suspended users can't log in or post until the suspension expires or is
+-- lifted, a NULL expires_at suspends until lifted
+CREATE TABLE suspensions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    report_id UUID REFERENCES reports(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    lifted_at TIMESTAMP
+);
+
+CREATE INDEX suspensions_user_id_idx ON suspensions (user_id, created_at DESC);
+
+CREATE TABLE warnings (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    report_id UUID REFERENCES reports(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX warnings_user_id_created_at_idx ON warnings (user_id, created_at DESC, id DESC);
+
+-- audit trail of everything moderators do. There are no foreign keys so
+-- the trail outlives the users, chirps and reports it mentions.
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    actor_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    report_id UUID,
+    user_id UUID,
+    chirp_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at DESC, id DESC);
+CREATE INDEX moderation_actions_report_id_idx ON moderation_actions (report_id, created_at) WHERE report_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE warnings;
+DROP TABLE suspensions;
+DROP TABLE reports;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- lifting a suspension records who lifted it and why
+ALTER TABLE suspensions ADD COLUMN lifted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE suspensions ADD COLUMN lift_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE suspensions DROP COLUMN lift_reason;
+ALTER TABLE suspensions DROP COLUMN lifted_by;
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+
+	"github.com/JonMunkholm/server/internal/database"
+)
+
+// withTx runs fn in a transaction, committing when it returns nil
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = fn(cfg.db.WithTx(tx))
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}